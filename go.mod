@@ -3,8 +3,10 @@ module github.com/ashutoshgngwr/android-translations
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/mattn/go-runewidth v0.0.9 // indirect
 	github.com/olekukonko/tablewriter v0.0.4
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/pflag v1.0.5
+	golang.org/x/text v0.3.7
 )
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// updateGolden rewrites the golden files with the actual outputs, e.g. after an
+// intended change of the report, with 'go test -run Golden -update'.
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+// runMainEnv is the environment variable that makes the test binary run main, so
+// that the tests can run the command line tool in a subprocess.
+const runMainEnv = "ANDROID_TRANSLATIONS_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// goldenCommitDates are the dates of the commits of the golden fixture project, i.e.
+// of the initial commit and of the commit that changes the default strings.
+var goldenCommitDates = []string{"2021-01-01T12:00:00Z", "2021-03-01T12:00:00Z"}
+
+// setupGoldenProject copies the golden fixture project to a new temporary directory
+// and commits it to a new git repository at goldenCommitDates[0]. It then replaces
+// its default strings with 'testdata/golden/strings-update.xml' in a commit at
+// goldenCommitDates[1] to outdate some translations. It returns the project
+// directory.
+func setupGoldenProject(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is required for the golden tests")
+	}
+
+	tmp, err := ioutil.TempDir("", "golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(tmp) })
+	dir := filepath.Join(tmp, "project")
+	src := filepath.Join("testdata", "golden", "project")
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		dest := filepath.Join(dir, strings.TrimPrefix(path, src))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(dest, content, 0644)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	git := func(date string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Golden", "-c", "user.email=golden@example.com"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	git(goldenCommitDates[0], "init", "-q")
+	git(goldenCommitDates[0], "add", "-A")
+	git(goldenCommitDates[0], "commit", "-q", "-m", "Add strings")
+
+	update, err := ioutil.ReadFile(filepath.Join("testdata", "golden", "strings-update.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "app", "src", "main", "res", "values", "strings.xml"), update, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	git(goldenCommitDates[1], "commit", "-q", "-a", "-m", "Update strings")
+	return dir
+}
+
+// runMain runs the command line tool with the given arguments in a subprocess and
+// returns its stdout. It fails the test if the tool exits with non-zero status.
+func runMain(t *testing.T, args ...string) []byte {
+	return runMainWithEnv(t, nil, args...)
+}
+
+// runMainWithEnv is like runMain, but also sets the given environment variables,
+// in 'key=value' form, for the subprocess.
+func runMainWithEnv(t *testing.T, env []string, args ...string) []byte {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append([]string{runMainEnv + "=1"}, env...)
+	for _, env := range os.Environ() {
+		// the environment of the test must not set any flags or GitHub Actions outputs
+		if !strings.HasPrefix(env, envPrefix) && !strings.HasPrefix(env, "GITHUB_") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("android-translations %s: %v\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+
+	return output
+}
+
+func TestGolden(t *testing.T) {
+	dir := setupGoldenProject(t)
+	tests := []struct {
+		golden string
+		args   []string
+	}{
+		{"report.json", nil},
+		{"report-envelope.json", []string{"--json-envelope", "--show-staleness", "--show-authors"}},
+		{"report-locale-names.md", []string{"--output-format", "markdown", "--locale-names"}},
+		{"report-columns.json", []string{"--columns", "name,missing,file,line"}},
+		{"report-columns.jsonl", []string{"--output-format", "jsonl", "--columns", "name,missing,file,line"}},
+		{"report-columns.toml", []string{"--output-format", "toml", "--columns", "name,missing,file,line"}},
+		{"report-columns.md", []string{"--output-format", "markdown", "--columns", "name,missing,file,line"}},
+		{"report-columns.txt", []string{"--output-format", "console", "--columns", "name,missing,file,line"}},
+		{"report.txt", []string{"--output-format", "console"}},
+		{"report-sections.json", []string{"--json-envelope", "--max-length-ratio", "1.2", "--supported-locales", "de,fr"}},
+		{"delta-array.json", []string{"--previous-report", filepath.Join("testdata", "golden", "report.json")}},
+		{"delta-envelope.md", []string{"--output-format", "markdown", "--previous-report", filepath.Join("testdata", "golden", "report-envelope.json")}},
+		{"report-sections.toml", []string{"--output-format", "toml", "--max-length-ratio", "1.2", "--supported-locales", "de,fr"}},
+	}
+
+	for _, test := range tests {
+		args := append([]string{"--quiet", "--project-dir", dir, "--now", "2021-06-01T00:00:00Z"}, test.args...)
+		output := runMain(t, args...)
+		path := filepath.Join("testdata", "golden", test.golden)
+		if *updateGolden {
+			if err := ioutil.WriteFile(path, output, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			continue
+		}
+
+		want, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(output, want) {
+			t.Errorf("output of %s doesn't match %s:\n%s", strings.Join(test.args, " "), path, output)
+		}
+	}
+}
+
+func TestGitHubStepSummaryWithAutoModules(t *testing.T) {
+	dir := setupGoldenProject(t)
+	summary := filepath.Join(dir, "summary.md")
+	env := []string{"GITHUB_STEP_SUMMARY=" + summary, "GITHUB_OUTPUT=" + filepath.Join(dir, "output")}
+	runMainWithEnv(t, env, "--quiet", "--project-dir", dir, "--auto-modules", "--github-actions")
+	content, err := ioutil.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the summary is Markdown regardless of the JSON output format
+	if !strings.HasPrefix(string(content), "# Android Translations: app\n") || !strings.Contains(string(content), "| `farewell`") {
+		t.Errorf("step summary = %q, want the Markdown report of the app module", content)
+	}
+}
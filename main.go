@@ -1,469 +1,1564 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
-	"encoding/xml"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
-	"github.com/olekukonko/tablewriter"
+	"github.com/ashutoshgngwr/android-translations/translations"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
-// doNotTranslateFileName as recognised by the Android Developer Tools
-// http://tools.android.com/recent/non-translatablestrings
-const doNotTranslateFileName = "donottranslate.xml"
+// version of the tool, set at build time with "-ldflags '-X main.version=...'"
+var version = "dev"
 
-// xmlTranslatable is a generic struct that can be embedded in other structs
-// to parse values for 'translatable' attribute
-type xmlTranslatable struct {
-	Translatable string `xml:"translatable,attr"`
+var (
+	projectDir      string   // root directory of the Android Project
+	outdatedLocales bool     // if true, also print potentially outdated locales
+	outputFormat    string   // output format, must be one of json, jsonl, toml, markdown, console, badge, diff, xlsx or po
+	poLocale        string   // locale of the translations in the PO output format
+	outputFile      string   // if set, write the output to this file instead of stdout
+	templateData    string   // if set, also write the complete report data model as JSON to this file
+	markdownTitle   string   // heading for markdown content
+	successMessage  string   // if set, replaces the "No missing translations found." line of the Markdown report
+	footer          string   // if set, replaces the attribution footer of the Markdown content
+	noFooter        bool     // if true, leave out the footer of the Markdown content
+	githubActions   bool     // if true, also call setGitHubActionsOutput to set action output
+	badgeYellowAt   float64  // minimum coverage (in percent) for a yellow badge
+	badgeGreenAt    float64  // minimum coverage (in percent) for a green badge
+	failOnDuplicate bool     // if true, exit with non-zero status if duplicate strings are found
+	failGlobs       []string // if not empty, exit with non-zero status if strings matching these glob patterns have gaps
+	fallbackSafe    []string // glob patterns of strings that may fall back to the default language when missing
+	columns         []string // ordered list of columns to render in the Markdown and console tables
+	scanArchives    bool     // if true, also find values files inside AAR, JAR and ZIP archives
+	printStats      bool     // if true, print the summary of counts to stderr
+	localeNames     bool     // if true, include human-readable locale names in the report
+	splitByLocale   string   // if not empty, write a separate report per locale in this directory
+	suggestNonTrans bool     // if true, suggest default strings that look non-translatable
+	reportNonTrans  bool     // if true, list the default strings that are excluded from the translation
+	sourceSets      []string // if not empty, only scan values files in these source sets
+	ignoreFiles     []string // glob patterns of values file names to ignore
+	resRoots        []string // if not empty, only find values files in these directories
+	requireRes      bool     // if true, only find values files in 'res' directories, resRoots or resDirPatterns
+	resDirPatterns  []string // glob patterns of resource directory names accepted by requireRes besides 'res'
+	valuesDirGlob   string   // glob pattern of values directory names
+	valuesFileExt   string   // extension of values files
+	inputFormat     string   // format of the scanned resource files, i.e. 'android' or 'arb'
+	namePrefixes    []string // if not empty, only report the strings whose names have any of these prefixes
+	nameRegex       string   // if not empty, only report the strings whose names match this regular expression
+	filesFrom       string   // if set, only scan the values files listed in this file, or stdin if '-'
+	files           []string // values files read from filesFrom
+	compareRefs     string   // if set, report the changes of the translations between these git refs, i.e. 'old..new'
+	translatedRef   string   // if set, report the gaps that the translations of this git ref fill
+	apkPath         string   // if set, report the strings of the values files that this APK doesn't have
+	inspectName     string   // if set, report the translations of this default string in each locale
+	importFile      string   // if set, write the translations in this PO or JSON file back to the values files
+	importLocale    string   // locale qualifier of the translations in importFile
+	autoModules     bool     // if true, scan each Gradle module on its own and report them separately
+	strictLocales   bool     // if true, exit with non-zero status if a locale qualifier is malformed
+	showComments    bool     // if true, include translator comments in the report
+	validateOnly    bool     // if true, only check that the values files are well-formed
+	formatOnly      bool     // if true, only check format specifiers and escaping of all strings
+	skipInvalid     bool     // if true, skip values files that can't be parsed instead of failing
+	baseline        string   // if not empty, only report the gaps that aren't in this baseline file
+	writeBaseline   bool     // if true, write the current gaps to the baseline file
+	previousReport  string   // if set, output the changes of the gaps since this JSON report instead of the report
+	defaultLocale   string   // if not empty, the locale to use as the source of truth instead of 'values'
+	overlayLocales  []string // locales that only override some default strings, so they are never missing
+	supportedLocs   []string // if not empty, report the locales that aren't in this list and the listed locales without strings
+	strictSupported bool     // if true, exit with non-zero status if a locale isn't in supportedLocs
+	maxRows         int      // if positive, maximum number of rows in the Markdown table
+	maxPreview      int      // if positive, maximum number of characters of the default values in the tables
+	showAuthors     bool     // if true, include committers of default strings and outdated translations
+	showStaleness   bool     // if true, include how far outdated translations lag behind default strings
+	checkPunct      bool     // if true, warn about punctuation and capitalization drift of translations
+	checkXliff      bool     // if true, warn about translations whose <xliff:g> placeholders differ from the default strings
+	maxLengthRatio  float64  // if positive, warn about translations longer than their default strings by more than this ratio
+	minLength       int      // minimum length of the translations to warn about with maxLengthRatio
+	checkEscapes    bool     // if true, warn about Android string escaping problems
+	arraysAtomic    bool     // if true, report each string array as a whole instead of its items
+	fullHistory     bool     // if true, fail in shallow git clones instead of skipping outdated detection
+	ignoreReformat  bool     // if true, don't report translations as outdated if their default value is unchanged
+	ignoreBlank     bool     // if true, leave out default strings whose values are only whitespace
+	blameRange      string   // lines blamed for the last modified time of the strings, must be one of element or value
+	repoURL         string   // if set, link each string to its line on the git host
+	gitRef          string   // git ref for the links, the current commit if empty
+	hostStyle       string   // URL style of the git host, must be one of github or gitlab
+	streamOutput    bool     // if true, write JSON records to stdout as soon as they are found
+	jsonCompact     bool     // if true, render JSON without indentation
+	jsonEnvelope    bool     // if true, wrap the JSON report in an envelope with the schema and tool versions
+	quiet           bool     // if true, don't print the progress to stderr
+	printConfig     bool     // if true, print the effective values of the flags and their sources, and exit
+	referenceDir    string   // if not empty, root directory of the reference Android project
+	minCoverage     float64  // minimum coverage (in percent) required for each locale
+	outdatedWeight  float64  // between 0 and 1, part of a translation that outdated translations count as in the coverage
+	failThreshold   int      // if not negative, maximum number of strings with missing or outdated translations
+	exitZero        bool     // if true, exit with zero status even if the gates, e.g. failThreshold, fail
+	sortOrder       string   // order of the strings in the report, must be one of name, source or missing-count
+	groupBy         string   // orientation of the report, must be one of string or locale
+	builtinAliases  bool     // if true, use translations.BuiltinLocaleAliases besides the locale-alias flag
+	cacheDir        string   // if set, cache the report in this directory keyed by the git HEAD and the flags
+	nowOverride     string   // if set, the current time in RFC 3339 form to use instead of the system clock
+
+	// minimum coverage (in percent) required for specific locales, overriding minCoverage
+	localeMinCoverage map[string]float64
+
+	// compiled nameRegex, nil if nameRegex is empty
+	namePattern *regexp.Regexp
+
+	// aliases of the locales in the report, e.g. 'he' for 'iw'
+	localeAliases map[string]string
+
+	// names of the strings that are only expected in some locales => those locales
+	stringLocales map[string][]string
+
+	// current time, i.e. the parsed nowOverride if it is set
+	now time.Time
+)
+
+// webhook settings
+var (
+	webhookURL      string        // if set, post the report to this webhook
+	webhookFormat   string        // body format of the webhook request, must be one of json or slack
+	webhookTimeout  time.Duration // timeout of the webhook request
+	webhookRequired bool          // if true, exit with non-zero status if the webhook request fails
+)
+
+// watch mode settings
+var (
+	watchMode     bool          // if true, print the report again whenever a values file changes
+	watchInterval time.Duration // interval of polling the values files for changes in watch mode
+)
+
+// parseFlags defines the flags, sets them from the command line and the environment
+// variables and validates them.
+func parseFlags() {
+	pflag.CommandLine.SortFlags = false
+	pflag.StringVar(&projectDir, "project-dir", ".", "Android Project's root directory")
+	pflag.BoolVar(&outdatedLocales, "outdated-locales", true, "If true, find potentially outdated translations")
+	pflag.StringVar(&outputFormat, "output-format", "json", "Output format. Must be 'json', 'jsonl', 'toml', 'markdown', 'console', 'badge', 'diff', 'xlsx' or 'po'")
+	pflag.StringVar(&poLocale, "po-locale", "", "Locale of the translations in the gettext PO output format, e.g. 'fr'. Required for PO format")
+	pflag.StringVar(&outputFile, "output-file", "", "If set, write the output to this file instead of stdout. Required for XLSX format")
+	pflag.StringVar(&templateData, "template-data-json", "", "If set, also write the complete report data model as JSON to this file for custom rendering")
+	pflag.StringVar(&webhookURL, "webhook-url", "", "If set, also post the report to this webhook URL")
+	pflag.StringVar(&webhookFormat, "webhook-format", "json", "Body format of the webhook request. Must be one of 'json' or 'slack'")
+	pflag.DurationVar(&webhookTimeout, "webhook-timeout", 10*time.Second, "Timeout of the webhook request")
+	pflag.BoolVar(&webhookRequired, "webhook-required", false, "If true, fail if the webhook request fails instead of warning")
+	pflag.StringVar(&markdownTitle, "markdown-title", "Android Translations", "Title for the Markdown content. Evaluated as a Go template with the date, project and version, and the counts of reports, e.g. '{{ .length }} strings need attention ({{ .date }})'")
+	pflag.StringVar(&successMessage, "success-message", "", "If set, replaces the 'No missing translations found.' line of the Markdown report")
+	pflag.StringVar(&footer, "footer", "", "If set, replaces the Android Translations attribution footer of the Markdown content")
+	pflag.BoolVar(&noFooter, "no-footer", false, "If true, leave out the footer of the Markdown content")
+	pflag.BoolVar(&githubActions, "github-actions", false, "Indicates if the runtime is GitHub Actions")
+	pflag.Float64Var(&badgeYellowAt, "badge-yellow-threshold", 50, "Minimum coverage percentage for a yellow badge")
+	pflag.Float64Var(&badgeGreenAt, "badge-green-threshold", 90, "Minimum coverage percentage for a green badge")
+	pflag.BoolVar(&failOnDuplicate, "fail-on-duplicate", false, "If true, fail when a string is defined more than once in a locale")
+	pflag.StringSliceVar(&failGlobs, "fail-glob", nil, "Comma-separated glob patterns of string names, e.g. 'app_name,*_error_critical'. If set, fail when a matching string has missing or outdated translations")
+	pflag.StringSliceVar(&fallbackSafe, "fallback-safe", nil, "Comma-separated glob patterns of string names that may fall back to the default language when missing, e.g. 'settings_*'. If set, the Markdown report separates them from the must-have strings")
+	pflag.StringSliceVar(&columns, "columns", nil, "Comma-separated ordered list of columns for the Markdown and console tables. The file, line and identical columns also add these fields to the JSON, JSON Lines and TOML reports")
+	pflag.BoolVar(&scanArchives, "scan-archives", false, "If true, also scan values files inside .aar, .jar and .zip archives")
+	pflag.BoolVar(&printStats, "print-stats", false, "If true, print counts of missing, outdated and affected strings to stderr")
+	pflag.BoolVar(&localeNames, "locale-names", false, "If true, include human-readable locale names in the report")
+	pflag.StringVar(&splitByLocale, "split-by-locale", "", "If set, also write a separate report for each locale in this directory")
+	pflag.BoolVar(&suggestNonTrans, "suggest-nontranslatable", false, "If true, suggest strings that look like they shouldn't be translated")
+	pflag.BoolVar(&reportNonTrans, "include-translatable-false-report", false, "If true, also list the strings marked translatable=\"false\" or defined in donottranslate.xml")
+	pflag.BoolVar(&autoModules, "auto-modules", false, "If true, find the Gradle modules and scan each of them on its own. Only for JSON and Markdown formats")
+	pflag.StringVar(&compareRefs, "compare", "", "If set, report the changes of the translations between two git refs, e.g. 'v1.0..v1.1'")
+	pflag.StringVar(&translatedRef, "diff-against-translated-branch", "", "If set, report which missing strings the translations of this branch fill, e.g. 'l10n'")
+	pflag.StringVar(&apkPath, "resources-from-apk", "", "If set, report the strings and locales of the values files that this APK doesn't have. Requires aapt2 on the PATH")
+	pflag.StringVar(&importFile, "import", "", "If set, write the translations in this gettext PO or JSON file back to the values files of import-locale")
+	pflag.StringVar(&importLocale, "import-locale", "", "Locale qualifier of the translations to import, e.g. 'fr' or 'b+sr+Latn'. Required for import")
+	pflag.StringVar(&inspectName, "string", "", "If set, only report the status, value and last modification of this default string in each locale, e.g. 'checkout_button'")
+	pflag.StringVar(&filesFrom, "files-from", "", "If set, only scan the values files listed in this file, one per line, or stdin if '-'")
+	pflag.StringSliceVar(&resRoots, "res-root", nil, "If set, only find values files in these directories, e.g. 'app/src/main/res'")
+	pflag.BoolVar(&requireRes, "require-res-parent", false, "If true, only find values files whose values directory is in a 'res' directory, a res-root or a directory matching res-dir-pattern")
+	pflag.StringSliceVar(&resDirPatterns, "res-dir-pattern", nil, "Comma-separated glob patterns of resource directory names that require-res-parent accepts besides 'res', e.g. 'res-*'")
+	pflag.StringVar(&valuesDirGlob, "values-dir-pattern", translations.DefaultValuesDirPattern, "Glob pattern of the names of values directories, including their qualifiers")
+	pflag.StringVar(&valuesFileExt, "values-file-ext", translations.DefaultValuesFileExt, "Extension of the values files in the values directories, matched case-insensitively")
+	pflag.StringVar(&inputFormat, "input-format", translations.AndroidInputFormat, "Format of the resource files. Must be 'android' (values XML files) or 'arb' (Flutter ARB files, e.g. 'intl_fr.arb')")
+	pflag.StringSliceVar(&ignoreFiles, "ignore-file", nil, "Ignore values files whose names match these glob patterns, e.g. 'constants*.xml'")
+	pflag.StringSliceVar(&namePrefixes, "name-prefix", nil, "Only report strings whose names have any of these prefixes, e.g. 'login_,chat_'")
+	pflag.StringVar(&nameRegex, "name-regex", "", "Only report strings whose names match this regular expression. Combined with name-prefix using OR")
+	pflag.StringSliceVar(&sourceSets, "source-set", nil, "Only scan these source sets, e.g. 'main,flavorA'. Later ones override earlier ones")
+	pflag.BoolVar(&strictLocales, "strict-locale-validation", false, "If true, fail on malformed locale qualifiers instead of skipping them")
+	pflag.BoolVar(&showComments, "show-comments", false, "If true, include XML comments directly above default strings in the report")
+	pflag.BoolVar(&validateOnly, "validate-only", false, "If true, only check values files for XML errors and exit with non-zero status if any are found")
+	pflag.BoolVar(&formatOnly, "format-check-only", false, "If true, only check format specifiers and escaping without git and exit with non-zero status on any problem")
+	pflag.BoolVar(&skipInvalid, "skip-invalid", false, "If true, skip values files that can't be parsed with a warning instead of failing")
+	pflag.StringVar(&baseline, "baseline", "", "If set, only report and fail on gaps that aren't in this baseline file")
+	pflag.BoolVar(&writeBaseline, "write-baseline", false, "If true, write the current gaps to the baseline file instead of comparing against it")
+	pflag.StringVar(&previousReport, "previous-report", "", "If set, output the newly missing, newly outdated and resolved gaps since this JSON report instead of the report")
+	pflag.StringVar(&defaultLocale, "default-locale", "", "If set, use strings of this locale, e.g. 'en', as the source of truth instead of 'values'")
+	pflag.StringSliceVar(&overlayLocales, "overlay-locales", nil, "Locales that only override some default strings, e.g. 'en-rGB'. They are never reported as missing")
+	pflag.StringSliceVar(&supportedLocs, "supported-locales", nil, "If set, report locales that aren't in this list and listed locales without any strings")
+	pflag.BoolVar(&strictSupported, "strict-locales", false, "If true, fail when a locale isn't in supported-locales")
+	pflag.IntVar(&maxRows, "max-rows", 0, "If positive, limit the Markdown table to this many rows. 0 means no limit")
+	pflag.IntVar(&maxPreview, "max-value-preview", 0, "If positive, truncate the default values in the Markdown tables to this many characters on a single line. 0 means no limit")
+	pflag.BoolVar(&showAuthors, "show-authors", false, "If true, include who last modified default strings and outdated translations")
+	pflag.BoolVar(&showStaleness, "show-staleness", false, "If true, include how far outdated translations lag behind default strings")
+	pflag.StringVar(&repoURL, "repo-url", "", "If set, link each string to its line in this repository, e.g. 'https://github.com/user/repo'")
+	pflag.StringVar(&gitRef, "git-ref", "", "Branch, tag or commit for the links to the strings. Defaults to the current commit")
+	pflag.StringVar(&hostStyle, "host-style", translations.GitHubHostStyle, "URL style of the git host for the links to the strings. Must be 'github' or 'gitlab'")
+	pflag.BoolVar(&ignoreReformat, "ignore-reformatting", false, "If true, don't report translations as outdated if only the formatting of their default strings changed")
+	pflag.BoolVar(&ignoreBlank, "ignore-whitespace-only-strings", false, "If true, don't report default strings whose values are only whitespace, e.g. spacers like '\\u0020'")
+	pflag.StringVar(&blameRange, "blame-tag-range", translations.ElementBlameRange, "Lines blamed for the last modified time of the strings. Must be 'element' (opening to closing tag) or 'value' (only the lines of the value)")
+	pflag.BoolVar(&fullHistory, "require-full-history", false, "If true, fail in shallow git clones instead of skipping outdated translations detection")
+	pflag.BoolVar(&arraysAtomic, "arrays-atomic", false, "If true, report a string array as a whole if any of its items is missing or outdated")
+	pflag.BoolVar(&checkEscapes, "check-escapes", false, "If true, warn about unescaped apostrophes, leading '@' or '?' and dangling backslashes")
+	pflag.BoolVar(&checkPunct, "check-punctuation", false, "If true, warn about punctuation, whitespace and capitalization drift of translations")
+	pflag.BoolVar(&checkXliff, "check-xliff", false, "If true, warn about translations whose <xliff:g> placeholder ids differ from the default strings")
+	pflag.Float64Var(&maxLengthRatio, "max-length-ratio", 0, "If positive, warn about translations longer than their default strings by more than this ratio, e.g. 2.0")
+	pflag.IntVar(&minLength, "min-length", 10, "Translations shorter than this many characters are never reported by max-length-ratio")
+	pflag.BoolVar(&jsonCompact, "json-compact", false, "If true, render JSON without indentation")
+	pflag.BoolVar(&jsonEnvelope, "json-envelope", false, "If true, wrap the JSON report in an object with the schema version, tool version and metadata")
+	pflag.BoolVar(&streamOutput, "stream", false, "If true, write JSON records to stdout as soon as they are found. Only for JSON format")
+	pflag.StringVar(&referenceDir, "reference-dir", "", "If set, report translations that differ from the ones in this Android project")
+	pflag.Float64Var(&outdatedWeight, "outdated-weight", 1, "Part of a translation, between 0 and 1, that outdated translations count as in the coverage")
+	pflag.IntVar(&failThreshold, "fail-threshold", -1, "If not negative, fail when more strings than this have missing or outdated translations")
+	pflag.BoolVar(&exitZero, "exit-zero-on-report", false, "If true, exit with zero status even if fail-threshold, fail-glob, min-coverage or the other gates, including strict-locale-validation, webhook-required, validate-only and format-check-only, fail, and only print them as warnings. Invalid flags and scan errors still fail")
+	pflag.Float64Var(&minCoverage, "min-coverage", 0, "Minimum coverage percentage required for each locale. Fails if a locale is below it")
+	localeAliasList := pflag.StringSlice("locale-alias", nil, "Comma-separated aliases for reporting locales, e.g. 'iw=he,zh-rTW=zh-Hant'")
+	pflag.BoolVar(&builtinAliases, "builtin-locale-aliases", true, "If true, report the legacy codes 'iw', 'in', 'ji' and 'tl' as 'he', 'id', 'yi' and 'fil'")
+	stringLocalesList := pflag.StringSlice("string-locales", nil, "Comma-separated strings that are only expected in some locales, e.g. 'app_eula_de:de,app_eula_de:at'")
+	localeMinCoverageList := pflag.StringSlice("locale-min-coverage", nil, "Comma-separated per-locale overrides for min-coverage, e.g. 'de:98,fr:90'")
+	pflag.StringVar(&sortOrder, "sort-order", "name", "Order of the strings. Must be 'name', 'source' (file and line) or 'missing-count'")
+	pflag.StringVar(&groupBy, "group-by", "string", "Orientation of the JSON and Markdown reports. Must be 'string' (the strings with their locales) or 'locale' (the locales with their strings)")
+	pflag.StringVar(&cacheDir, "cache-dir", "", "If set, reuse the report cached in this directory for the same git HEAD and flags")
+	pflag.StringVar(&nowOverride, "now", "", "If set, use this RFC 3339 time, e.g. '2021-01-02T15:04:05Z', instead of the current time for reproducible reports")
+	pflag.BoolVarP(&quiet, "quiet", "q", false, "If true, don't print the progress to stderr")
+	pflag.BoolVar(&watchMode, "watch", false, "If true, print the report again whenever a values file changes, without git history. Stops on Ctrl+C")
+	pflag.DurationVar(&watchInterval, "watch-interval", time.Second, "Interval of polling the modification times of the values files in watch mode. Changes are picked up once the files stay unchanged for a whole interval")
+	pflag.BoolVar(&printConfig, "print-config", false, "If true, print the effective value of each flag and whether it comes from the default, an environment variable or the command line, and exit")
+	pflag.Parse()
+	setFlagsFromEnv()
+	if printConfig {
+		printEffectiveConfig()
+		os.Exit(0)
+	}
+
+	switch outputFormat {
+	case "json", "jsonl", "toml", "markdown", "console", "badge", "diff", "xlsx", "po":
+		break
+	default:
+		fatal(fmt.Sprintf("unknow output format %s", outputFormat))
+	}
+
+	// the plain JSON report is an array of strings without room for other sections
+	if outputFormat == "json" && reportNonTrans && !jsonEnvelope {
+		fatal("include-translatable-false-report requires json-envelope with json output format")
+	}
+
+	if outputFormat == "po" {
+		if poLocale == "" {
+			fatal("po output format requires po-locale")
+		}
+
+		// the PO file is an export of a single locale rather than a report
+		if splitByLocale != "" || autoModules || watchMode {
+			fatal("po output format can't be used with split-by-locale, auto-modules or watch")
+		}
+	} else if poLocale != "" {
+		fatal("po-locale requires po output format")
+	}
+
+	// colors only make sense for a terminal, see https://no-color.org
+	consoleColors = outputFile == "" && isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+
+	switch sortOrder {
+	case "name", "source", "missing-count":
+		break
+	default:
+		fatal(fmt.Sprintf("unknown sort order %s", sortOrder))
+	}
+
+	switch blameRange {
+	case translations.ElementBlameRange, translations.ValueBlameRange:
+		break
+	default:
+		fatal(fmt.Sprintf("unknown blame-tag-range %s", blameRange))
+	}
+
+	switch groupBy {
+	case "string":
+		break
+	case "locale":
+		if outputFormat != "json" && outputFormat != "markdown" {
+			fatal("group-by locale is only supported with json and markdown output formats")
+		}
+
+		if streamOutput {
+			fatal("group-by locale can't be used with stream")
+		}
+	default:
+		fatal(fmt.Sprintf("unknown group-by %s", groupBy))
+	}
+
+	if filesFrom != "" {
+		var err error
+		if files, err = readFileList(filesFrom); err != nil {
+			fatal(err)
+		}
+	}
+
+	if watchMode {
+		if watchInterval <= 0 {
+			fatal("watch-interval must be positive")
+		}
+
+		if streamOutput || compareRefs != "" || translatedRef != "" || autoModules || validateOnly || formatOnly || githubActions {
+			fatal("watch can't be used with stream, compare, diff-against-translated-branch, auto-modules, validate-only, format-check-only or github-actions")
+		}
+
+		if outputFormat == "xlsx" {
+			fatal("watch can't be used with xlsx output format")
+		}
+	}
+
+	if footer != "" && noFooter {
+		fatal("footer can't be used with no-footer")
+	}
+
+	if strictSupported && len(supportedLocs) == 0 {
+		fatal("strict-locales requires supported-locales")
+	}
+
+	for _, pattern := range ignoreFiles {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fatal(fmt.Sprintf("invalid ignore-file pattern %q", pattern))
+		}
+	}
+
+	for _, pattern := range failGlobs {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fatal(fmt.Sprintf("invalid fail-glob pattern %q", pattern))
+		}
+	}
+
+	for _, pattern := range fallbackSafe {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fatal(fmt.Sprintf("invalid fallback-safe pattern %q", pattern))
+		}
+	}
+
+	if len(resDirPatterns) > 0 && !requireRes {
+		fatal("res-dir-pattern requires require-res-parent")
+	}
+
+	for _, pattern := range resDirPatterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fatal(fmt.Sprintf("invalid res-dir-pattern %q", pattern))
+		}
+	}
+
+	if _, err := filepath.Match(valuesDirGlob, ""); err != nil || valuesDirGlob == "" {
+		fatal(fmt.Sprintf("invalid values-dir-pattern %q", valuesDirGlob))
+	}
+
+	if !strings.HasPrefix(valuesFileExt, ".") || len(valuesFileExt) < 2 {
+		fatal(fmt.Sprintf("invalid values-file-ext %q, must start with a dot, e.g. '.xml'", valuesFileExt))
+	}
+
+	if nameRegex != "" {
+		var err error
+		if namePattern, err = regexp.Compile(nameRegex); err != nil {
+			fatal(errors.Wrapf(err, "invalid name-regex %q", nameRegex))
+		}
+	}
+
+	switch hostStyle {
+	case translations.GitHubHostStyle, translations.GitLabHostStyle:
+		break
+	default:
+		fatal(fmt.Sprintf("unknown host style %s", hostStyle))
+	}
+
+	if webhookFormat != "json" && webhookFormat != "slack" {
+		fatal(fmt.Sprintf("unknown webhook format %s", webhookFormat))
+	}
+
+	if badgeYellowAt > badgeGreenAt {
+		fatal("badge-yellow-threshold must not be greater than badge-green-threshold")
+	}
+
+	if len(columns) == 0 && outputFormat == "console" {
+		// the compact table only has the names and the locales by default
+		columns = []string{"name", "missing"}
+		if outdatedLocales {
+			columns = append(columns, "outdated")
+		}
+	} else if len(columns) == 0 {
+		columns = []string{"index", "name", "value", "missing"}
+		if outdatedLocales {
+			columns = append(columns, "outdated")
+		}
+
+		if showComments {
+			columns = append(columns, "comment", "hint")
+		}
+
+		if showAuthors {
+			columns = append(columns, "author")
+		}
+
+		if showStaleness {
+			columns = append(columns, "staleness")
+		}
+	}
+
+	for _, column := range columns {
+		if _, ok := tableColumns[column]; !ok {
+			fatal(fmt.Sprintf("unknown column %s", column))
+		}
+	}
+
+	localeMinCoverage = map[string]float64{}
+	for _, item := range *localeMinCoverageList {
+		split := strings.SplitN(item, ":", 2)
+		if len(split) < 2 {
+			fatal(fmt.Sprintf("invalid locale-min-coverage %s, must be in 'locale:percent' form", item))
+		}
+
+		coverage, err := strconv.ParseFloat(split[1], 64)
+		if err != nil {
+			fatal(fmt.Sprintf("invalid locale-min-coverage %s, must be in 'locale:percent' form", item))
+		}
+
+		localeMinCoverage[split[0]] = coverage
+	}
+
+	localeAliases = map[string]string{}
+	if builtinAliases {
+		for locale, alias := range translations.BuiltinLocaleAliases {
+			localeAliases[locale] = alias
+		}
+	}
+
+	for _, item := range *localeAliasList {
+		split := strings.SplitN(item, "=", 2)
+		if len(split) < 2 || split[0] == "" || split[1] == "" {
+			fatal(fmt.Sprintf("invalid locale-alias %s, must be in 'locale=alias' form", item))
+		}
+
+		localeAliases[split[0]] = split[1]
+	}
+
+	now = time.Now()
+	if nowOverride != "" {
+		var err error
+		if now, err = time.Parse(time.RFC3339, nowOverride); err != nil {
+			fatal(errors.Wrapf(err, "invalid now %q", nowOverride))
+		}
+	}
+
+	stringLocales = map[string][]string{}
+	for _, item := range *stringLocalesList {
+		split := strings.SplitN(item, ":", 2)
+		if len(split) < 2 || split[0] == "" || split[1] == "" {
+			fatal(fmt.Sprintf("invalid string-locales %s, must be in 'name:locale' form", item))
+		}
+
+		stringLocales[split[0]] = append(stringLocales[split[0]], split[1])
+	}
+
+	if outdatedWeight < 0 || outdatedWeight > 1 {
+		fatal("outdated-weight must be between 0 and 1")
+	}
+
+	if maxLengthRatio < 0 || minLength < 0 {
+		fatal("max-length-ratio and min-length must not be negative")
+	}
+
+	if maxRows < 0 {
+		fatal("max-rows must not be negative")
+	}
+
+	if maxPreview < 0 {
+		fatal("max-value-preview must not be negative")
+	}
+
+	// the title is evaluated as a template by all outputs, see renderTitle
+	if _, err := template.New("title").Parse(markdownTitle); err != nil {
+		fatal(errors.Wrapf(err, "invalid markdown-title %q", markdownTitle))
+	}
+
+	// XLSX is binary, so it isn't written to stdout, the GitHub Actions output or the
+	// per-locale reports, which already are the sheets of the XLSX report
+	if outputFormat == "xlsx" && (outputFile == "" || splitByLocale != "") {
+		fatal("xlsx output format requires output-file and can't be used with split-by-locale")
+	}
+
+	if validateOnly && formatOnly {
+		fatal("validate-only can't be used with format-check-only")
+	}
+
+	if writeBaseline && baseline == "" {
+		fatal("write-baseline requires a baseline file")
+	}
+
+	if previousReport != "" {
+		if outputFormat != "json" && outputFormat != "markdown" {
+			fatal("previous-report is only supported with json and markdown output formats")
+		}
+
+		// the previous report has all the gaps, so they are compared to all of them
+		if streamOutput || autoModules || baseline != "" || splitByLocale != "" {
+			fatal("previous-report can't be used with stream, auto-modules, baseline or split-by-locale")
+		}
+	}
+
+	if autoModules {
+		if outputFormat != "json" && outputFormat != "markdown" {
+			fatal("auto-modules is only supported with json and markdown output formats")
+		}
+
+		// these need a single scan of the whole project
+		if streamOutput || compareRefs != "" || filesFrom != "" || splitByLocale != "" || baseline != "" {
+			fatal("auto-modules can't be used with stream, compare, files-from, split-by-locale or baseline")
+		}
+	}
+
+	if compareRefs != "" {
+		if refs := strings.SplitN(compareRefs, "..", 2); len(refs) != 2 || refs[0] == "" || refs[1] == "" {
+			fatal(fmt.Sprintf("invalid compare refs %q, expected 'old..new'", compareRefs))
+		}
+
+		if outputFormat != "json" && outputFormat != "markdown" {
+			fatal("compare is only supported with json and markdown output formats")
+		}
+
+		if streamOutput {
+			fatal("stream can't be used with compare")
+		}
+	}
+
+	if translatedRef != "" {
+		if outputFormat != "json" && outputFormat != "markdown" {
+			fatal("diff-against-translated-branch is only supported with json and markdown output formats")
+		}
+
+		if streamOutput || compareRefs != "" || autoModules || filesFrom != "" {
+			fatal("diff-against-translated-branch can't be used with stream, compare, auto-modules or files-from")
+		}
+	}
+
+	if apkPath != "" {
+		if outputFormat != "json" && outputFormat != "markdown" {
+			fatal("resources-from-apk is only supported with json and markdown output formats")
+		}
+
+		if streamOutput || compareRefs != "" || translatedRef != "" || autoModules || watchMode {
+			fatal("resources-from-apk can't be used with stream, compare, diff-against-translated-branch, auto-modules or watch")
+		}
+	}
+
+	if inspectName != "" {
+		if outputFormat != "json" && outputFormat != "markdown" {
+			fatal("string is only supported with json and markdown output formats")
+		}
+
+		if streamOutput || compareRefs != "" || translatedRef != "" || apkPath != "" || autoModules || watchMode {
+			fatal("string can't be used with stream, compare, diff-against-translated-branch, resources-from-apk, auto-modules or watch")
+		}
+	}
+
+	if importFile != "" {
+		if ext := filepath.Ext(importFile); ext != ".po" && ext != ".json" {
+			fatal(fmt.Sprintf("unknown import file type %q, must be .po or .json", ext))
+		}
+
+		if importLocale == "" {
+			fatal("import requires import-locale")
+		}
+
+		if outputFormat != "json" && outputFormat != "markdown" {
+			fatal("import is only supported with json and markdown output formats")
+		}
+
+		if streamOutput || compareRefs != "" || translatedRef != "" || apkPath != "" || inspectName != "" || autoModules || watchMode {
+			fatal("import can't be used with stream, compare, diff-against-translated-branch, resources-from-apk, string, auto-modules or watch")
+		}
+	} else if importLocale != "" {
+		fatal("import-locale requires import")
+	}
+
+	// only the report of the missing and outdated translations is posted
+	if webhookURL != "" && (compareRefs != "" || translatedRef != "" || apkPath != "" || inspectName != "" || importFile != "" ||
+		autoModules || watchMode || validateOnly || formatOnly || outputFormat == "po") {
+		fatal("webhook-url can't be used with compare, diff-against-translated-branch, resources-from-apk, string, import, auto-modules, " +
+			"watch, validate-only, format-check-only or po output format")
+	}
+
+	// the data model is only that of the report of the missing and outdated translations
+	if templateData != "" && (compareRefs != "" || translatedRef != "" || apkPath != "" || inspectName != "" || importFile != "" ||
+		autoModules || watchMode || validateOnly || formatOnly || outputFormat == "po") {
+		fatal("template-data-json can't be used with compare, diff-against-translated-branch, resources-from-apk, string, import, auto-modules, " +
+			"watch, validate-only, format-check-only or po output format")
+	}
+
+	switch inputFormat {
+	case translations.AndroidInputFormat:
+		break
+	case translations.ARBInputFormat:
+		// these read or write the values XML files on their own
+		if validateOnly || formatOnly || importFile != "" || outputFormat == "po" || outputFormat == "diff" || apkPath != "" || translatedRef != "" || autoModules ||
+			scanArchives || filesFrom != "" || reportNonTrans || ignoreReformat || checkEscapes || checkXliff {
+			fatal("arb input format can't be used with validate-only, format-check-only, import, po or diff output formats, resources-from-apk, " +
+				"diff-against-translated-branch, auto-modules, scan-archives, files-from, include-translatable-false-report, ignore-reformatting, check-escapes or check-xliff")
+		}
+	default:
+		fatal(fmt.Sprintf("unknown input format %s", inputFormat))
+	}
+
+	if streamOutput {
+		if outputFormat != "json" && outputFormat != "jsonl" {
+			fatal("stream is only supported with json and jsonl output formats")
+		}
+
+		if sortOrder != "name" {
+			fatal("stream is only supported with name sort order")
+		}
+
+		// these need all the strings after the scan
+		if splitByLocale != "" || baseline != "" || githubActions || outputFile != "" {
+			fatal("stream can't be used with split-by-locale, baseline, github-actions or output-file")
+		}
+
+		if cacheDir != "" || jsonEnvelope || templateData != "" {
+			fatal("stream can't be used with cache-dir, json-envelope or template-data-json")
+		}
+	}
+}
+
+func main() {
+	parseFlags()
+	if validateOnly {
+		validate()
+		return
+	}
+
+	if formatOnly {
+		checkFormat()
+		return
+	}
+
+	if compareRefs != "" {
+		compare()
+		return
+	}
+
+	if translatedRef != "" {
+		compareTranslatedBranch()
+		return
+	}
+
+	if apkPath != "" {
+		compareArtifact()
+		return
+	}
+
+	if inspectName != "" {
+		inspectString()
+		return
+	}
+
+	if importFile != "" {
+		importTranslations()
+		return
+	}
+
+	if outputFormat == "po" {
+		exportPO()
+		return
+	}
+
+	if watchMode {
+		watch()
+		return
+	}
+
+	if autoModules {
+		scanModules()
+		return
+	}
+
+	var stream *jsonStream
+	var onString func(translations.StringResource)
+	failing := make([]string, 0) // names of the strings with gaps that match fail-glob
+	if streamOutput {
+		stream = &jsonStream{w: os.Stdout, lines: outputFormat == "jsonl", compact: jsonCompact}
+		onString = func(res translations.StringResource) {
+			stream.mustWrite(withSelectedField(res.WithLocaleAliases(localeAliases)))
+			if matchesGlob(res.Name, failGlobs) {
+				failing = append(failing, res.Name)
+			}
+		}
+	}
+
+	var onProgress func(done, total int)
+	if !quiet && isTerminal(os.Stderr) {
+		onProgress = printProgress
+	}
+
+	opts := scanOptions()
+	opts.OnString, opts.OnProgress = onString, onProgress
+	var report translations.Report
+	var err error
+	if cacheDir != "" {
+		report, _, err = translations.ScanCached(projectDir, cacheDir, flagsCacheKey(), opts)
+	} else {
+		report, err = translations.Scan(projectDir, opts)
+	}
+
+	if err != nil {
+		fatal(err)
+	}
+
+	if stream != nil {
+		stream.close()
+	}
+
+	if writeBaseline {
+		if err := translations.WriteBaseline(baseline, report.Gaps()); err != nil {
+			fatal(err)
+		}
+	} else if baseline != "" {
+		gaps, err := translations.ReadBaseline(baseline)
+		if err != nil {
+			fatal(err)
+		}
+
+		report = report.ApplyBaseline(gaps)
+	}
+
+	report = report.WithLocaleAliases(localeAliases)
+	defaultLanguage = report.DefaultLanguage
+
+	sortStrings(report.Strings)
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+
+	title := renderTitle(reportTitleData(len(report.Strings), report.MissingCount, report.OutdatedCount, report.AffectedCount))
+	output := ""
+	if previousReport != "" {
+		gaps, err := translations.ReadReportGaps(previousReport)
+		if err != nil {
+			fatal(err)
+		}
+
+		output = mustRenderGapDelta(title, translations.CompareGaps(gaps, report.Gaps()))
+	} else if stream == nil {
+		output = mustRenderReport(title, report)
+	}
+
+	if splitByLocale != "" {
+		if err := writeLocaleReports(splitByLocale, report); err != nil {
+			fatal(err)
+		}
+	}
+
+	if templateData != "" {
+		if err := ioutil.WriteFile(templateData, []byte(mustRenderJSON(report.Model())+"\n"), 0644); err != nil {
+			fatal(errors.Wrapf(err, "unable to write template data to %s", templateData))
+		}
+	}
+
+	if printStats {
+		fmt.Fprintln(os.Stderr, "missing_count:", report.MissingCount)
+		fmt.Fprintln(os.Stderr, "outdated_count:", report.OutdatedCount)
+		fmt.Fprintln(os.Stderr, "total_affected:", report.AffectedCount)
+		fmt.Fprintln(os.Stderr, "missing_histogram:", formatHistogram(report.MissingHistogram(), len(report.Locales)))
+		for _, locale := range report.Locales {
+			counts := make([]string, 0, len(report.TypeCounts[locale]))
+			resTypes := []string{translations.StringType, translations.ArrayItemType, translations.ArrayType, translations.PluralItemType}
+			for _, resType := range resTypes {
+				if count, ok := report.TypeCounts[locale][resType]; ok {
+					counts = append(counts, fmt.Sprintf("%s=%d/%d", resType, count.Translated, count.Total))
+				}
+			}
+
+			fmt.Fprintf(os.Stderr, "translated[%s]: %s\n", locale, strings.Join(counts, " "))
+		}
+	}
+
+	if githubActions {
+		if outputFormat != "xlsx" {
+			setGitHubActionsOutput("report", output)
+		}
+
+		setGitHubActionsOutput("missing_count", strconv.Itoa(report.MissingCount))
+		setGitHubActionsOutput("outdated_count", strconv.Itoa(report.OutdatedCount))
+		setGitHubActionsOutput("total_affected", strconv.Itoa(report.AffectedCount))
+		fmt.Println()
+
+		summary := output
+		if outputFormat != "markdown" {
+			summary = mustRenderMarkdown(title, report)
+		}
+
+		if err := appendGitHubStepSummary(summary); err != nil {
+			fmt.Fprintln(os.Stderr, "warning:", err)
+		}
+	}
+
+	if stream == nil {
+		printOutput(output)
+	}
+
+	if webhookURL != "" {
+		if err := postWebhook(title, report); err != nil {
+			if webhookRequired {
+				failGate(err.Error())
+			} else {
+				fmt.Fprintln(os.Stderr, "warning:", err)
+			}
+		}
+	}
+
+	if strictLocales && len(report.InvalidLocales) > 0 {
+		failGate(fmt.Sprintf("found unrecognized locale qualifiers: %s", strings.Join(report.InvalidLocales, ", ")))
+	}
+
+	if failOnDuplicate && len(report.Duplicates) > 0 {
+		failGate(fmt.Sprintf("found %d duplicate string definition(s)", len(report.Duplicates)))
+	}
+
+	if strictSupported && len(report.UnexpectedLocales) > 0 {
+		failGate(fmt.Sprintf("found unsupported locales: %s", strings.Join(report.UnexpectedLocales, ", ")))
+	}
+
+	if below := report.LocalesBelowCoverage(minCoverage, localeMinCoverage); len(below) > 0 {
+		for i, locale := range below {
+			below[i] = fmt.Sprintf("%s (%.2f%%)", locale, report.LocaleCoverage[locale])
+		}
+
+		failGate(fmt.Sprintf("locales below minimum coverage: %s", strings.Join(below, ", ")))
+	}
+
+	if failThreshold >= 0 && !writeBaseline && report.AffectedCount > failThreshold {
+		const msgFmt = "found %d string(s) with missing or outdated translations, more than the fail-threshold of %d"
+		failGate(fmt.Sprintf(msgFmt, report.AffectedCount, failThreshold))
+	}
+
+	// the fail-threshold takes precedence since it applies to the gaps that aren't in the baseline
+	if failThreshold < 0 && baseline != "" && !writeBaseline && report.AffectedCount > 0 {
+		failGate(fmt.Sprintf("found %d string(s) with gaps that aren't in the baseline", report.AffectedCount))
+	}
+
+	for _, res := range report.Strings {
+		if matchesGlob(res.Name, failGlobs) {
+			failing = append(failing, res.Name)
+		}
+	}
+
+	if len(failing) > 0 && !writeBaseline {
+		failGate(fmt.Sprintf("found missing or outdated translations of strings matching fail-glob: %s", strings.Join(failing, ", ")))
+	}
 }
 
-// IsTranslatable returns false if the value of 'Translatable' attr was set
-// to 'false'. Returns true otherwise.
-func (res *xmlTranslatable) IsTranslatable() bool {
-	return !strings.EqualFold("false", res.Translatable)
+// matchesGlob checks if the given string name matches any of the given glob
+// patterns. The items of string arrays and plurals, e.g. 'planets[0]', also match
+// the patterns that match the names of their parents.
+func matchesGlob(name string, patterns []string) bool {
+	parent := name
+	if i := strings.IndexByte(name, '['); i > 0 {
+		parent = name[:i]
+	}
+
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+
+		if matched, _ := filepath.Match(pattern, parent); matched {
+			return true
+		}
+	}
+
+	return false
 }
 
-// xmlStringResources declares data structure for unmarshalling 'resources' tag in
-// Android values XML files.
-type xmlStringResources struct {
-	xml.Name     `xml:"resources"`
-	Strings      []xmlStringResource      `xml:"string"`
-	StringArrays []xmlStringArrayResource `xml:"string-array"`
+// scanOptions returns the options for Scan set by the flags.
+func scanOptions() translations.Options {
+	return translations.Options{
+		ScanArchives:           scanArchives,
+		SourceSets:             sourceSets,
+		IgnoreFiles:            ignoreFiles,
+		ResRoots:               resRoots,
+		RequireResParent:       requireRes,
+		ResDirPatterns:         resDirPatterns,
+		ValuesDirPattern:       valuesDirGlob,
+		ValuesFileExt:          valuesFileExt,
+		InputFormat:            inputFormat,
+		Files:                  files,
+		StrictLocaleValidation: strictLocales && !exitZero, // else failGate warns about the invalid locales
+		ShowComments:           showComments,
+		LocaleNames:            localeNames,
+		SuggestNonTranslatable: suggestNonTrans,
+		ReportNonTranslatable:  reportNonTrans,
+		OutdatedDiffs:          outputFormat == "diff",
+		SkipInvalid:            skipInvalid,
+		DefaultLocale:          defaultLocale,
+		OverlayLocales:         overlayLocales,
+		SupportedLocales:       supportedLocs,
+		StringLocales:          stringLocales,
+		Now:                    now,
+		ShowAuthors:            showAuthors,
+		ShowStaleness:          showStaleness,
+		CheckPunctuation:       checkPunct,
+		CheckXliff:             checkXliff,
+		MaxLengthRatio:         maxLengthRatio,
+		MinLength:              minLength,
+		CheckEscapes:           checkEscapes,
+		ArraysAtomic:           arraysAtomic,
+		RequireFullHistory:     fullHistory,
+		SkipOutdated:           !outdatedLocales,
+		IgnoreReformatting:     ignoreReformat,
+		IgnoreWhitespaceOnly:   ignoreBlank,
+		BlameRange:             blameRange,
+		OutdatedPenalty:        1 - outdatedWeight,
+		RepoURL:                repoURL,
+		GitRef:                 gitRef,
+		HostStyle:              hostStyle,
+		ReferenceDir:           referenceDir,
+		NamePrefixes:           namePrefixes,
+		NamePattern:            namePattern,
+	}
 }
 
-// xmlStringResource declares data structure for unmarshalling 'string' tags in Android
-// values XML files.
-type xmlStringResource struct {
-	Name         string    `xml:"name,attr"`
-	Value        string    `xml:",chardata"`
-	LastModified time.Time `xml:"-"`
-	xmlTranslatable
+// envPrefix is the prefix of environment variables that set the flags, e.g.
+// 'ANDROID_TRANSLATIONS_OUTPUT_FORMAT' for '--output-format'.
+const envPrefix = "ANDROID_TRANSLATIONS_"
+
+// flagsCacheKey returns the version of the tool, the values of all flags, except
+// '--cache-dir', and the list of files read from '--files-from' for keying the
+// cached reports, so that a new version of the tool doesn't load the reports cached
+// by an older one.
+func flagsCacheKey() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "version=%s\n", version)
+	pflag.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name != "cache-dir" {
+			fmt.Fprintf(&b, "%s=%s\n", flag.Name, flag.Value)
+		}
+	})
+
+	for _, file := range files {
+		fmt.Fprintln(&b, file)
+	}
+
+	return b.String()
 }
 
-type xmlStringArrayResource struct {
-	Name string `xml:"name,attr"`
-	// since items have only the value, we can re-use xmlStringResource struct
-	Items []xmlStringResource `xml:"item"`
-	xmlTranslatable
+// envFlags maps the names of the flags set by setFlagsFromEnv to the names of the
+// environment variables that set them.
+var envFlags = map[string]string{}
+
+// setFlagsFromEnv sets each flag that isn't set on the command line from its
+// environment variable, if present. Thus, the command line flags take precedence
+// over the environment variables, which take precedence over the defaults.
+func setFlagsFromEnv() {
+	pflag.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed {
+			return
+		}
+
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(flag.Name, "-", "_"))
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+
+		if err := pflag.Set(flag.Name, value); err != nil {
+			fatal(errors.Wrapf(err, "invalid value %q for %s", value, name))
+		}
+
+		envFlags[flag.Name] = name
+	})
 }
 
-// localeStringsMap declares the type to map locales => string_name => stringResource
-type localeStringsMap map[string]map[string]xmlStringResource
+// printEffectiveConfig prints the effective value of each flag, except
+// '--print-config', to stdout along with its source, i.e. the default, the
+// environment variable or the command line, to debug their precedence.
+func printEffectiveConfig() {
+	pflag.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "print-config" {
+			return
+		}
+
+		source := "default"
+		if name, ok := envFlags[flag.Name]; ok {
+			source = "env " + name
+		} else if flag.Changed {
+			source = "command line"
+		}
 
-// stringResource declares the output structure for a single string resource.
-type stringResource struct {
-	Name            string   `json:"name"`
-	Value           string   `json:"value"`
-	MissingLocales  []string `json:"missing_locales"`
-	OutdatedLocales []string `json:"outdated_locales"`
+		fmt.Printf("--%s=%s # %s\n", flag.Name, flag.Value, source)
+	})
 }
 
-// MissingLocalesString joins the MissingLocales slice using ", " separator
-func (res stringResource) MissingLocalesString() string {
-	if len(res.MissingLocales) == 0 {
-		return "-"
-	}
+// sortStrings sorts the given strings in the requested order. The strings are already
+// sorted by their names, so the name order is used for the ties of other orders.
+func sortStrings(strs []translations.StringResource) {
+	switch sortOrder {
+	case "source":
+		sort.SliceStable(strs, func(i, j int) bool {
+			if strs[i].File != strs[j].File {
+				return strs[i].File < strs[j].File
+			}
 
-	return strings.Join(res.MissingLocales, ", ")
+			return strs[i].Line < strs[j].Line
+		})
+	case "missing-count":
+		sort.SliceStable(strs, func(i, j int) bool {
+			return len(strs[i].MissingLocales) > len(strs[j].MissingLocales)
+		})
+	}
 }
 
-// OutdatesLocalesString joins the OutdatesLocales slice using ", " separator
-func (res stringResource) OutdatedLocalesString() string {
-	if len(res.OutdatedLocales) == 0 {
-		return "-"
+// validate reports the problems found in all values files to stderr and exits with
+// non-zero status if there are any, unless '--exit-zero-on-report' is set.
+func validate() {
+	validationErrors, err := translations.Validate(projectDir, translations.Options{
+		ScanArchives: scanArchives,
+		SourceSets:   sourceSets,
+		IgnoreFiles:  ignoreFiles,
+		ResRoots:     resRoots,
+		Files:        files,
+
+		RequireResParent: requireRes,
+		ResDirPatterns:   resDirPatterns,
+		ValuesDirPattern: valuesDirGlob,
+		ValuesFileExt:    valuesFileExt,
+	})
+
+	if err != nil {
+		fatal(err)
 	}
 
-	return strings.Join(res.OutdatedLocales, ", ")
-}
+	for _, validationErr := range validationErrors {
+		fmt.Fprintln(os.Stderr, "invalid:", validationErr)
+	}
 
-// stringResources is a named type for stringResource slice that implements
-// the sort.Interface for sorting slices.
-type stringResources []stringResource
+	if len(validationErrors) > 0 {
+		failGate(fmt.Sprintf("found %d problem(s) in values files", len(validationErrors)))
+	}
+}
 
-func (res stringResources) Len() int           { return len(res) }
-func (res stringResources) Swap(i, j int)      { res[i], res[j] = res[j], res[i] }
-func (res stringResources) Less(i, j int) bool { return res[i].Name < res[j].Name }
+// checkFormat reports the format specifier and escaping problems of all strings to
+// stderr and exits with non-zero status if there are any, unless
+// '--exit-zero-on-report' is set.
+func checkFormat() {
+	problems, err := translations.CheckFormat(projectDir, translations.Options{
+		DefaultLocale: defaultLocale,
+		ScanArchives:  scanArchives,
+		SourceSets:    sourceSets,
+		IgnoreFiles:   ignoreFiles,
+		ResRoots:      resRoots,
+		Files:         files,
+		ArraysAtomic:  arraysAtomic,
+
+		RequireResParent: requireRes,
+		ResDirPatterns:   resDirPatterns,
+		ValuesDirPattern: valuesDirGlob,
+		ValuesFileExt:    valuesFileExt,
+	})
 
-// defaultLocale declares the constant to identify default string resources (resources
-// in 'values' [no suffix] directory)
-const defaultLocale = "default"
+	if err != nil {
+		fatal(err)
+	}
 
-var (
-	projectDir      string // root directory of the Android Project
-	outdatedLocales bool   // if true, also print potentially outdated locales
-	outputFormat    string // output format, must be one of markdown or json
-	markdownTitle   string // heading for markdown content
-	githubActions   bool   // if true, also call setGitHubActionsOutput to set action output
-)
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
 
-func init() {
-	pflag.CommandLine.SortFlags = false
-	pflag.StringVar(&projectDir, "project-dir", ".", "Android Project's root directory")
-	pflag.BoolVar(&outdatedLocales, "outdated-locales", true, "If true, find potentially outdated translations")
-	pflag.StringVar(&outputFormat, "output-format", "json", "Output format. Must be 'json' or 'markdown'")
-	pflag.StringVar(&markdownTitle, "markdown-title", "Android Translations", "Title for the Markdown content")
-	pflag.BoolVar(&githubActions, "github-actions", false, "Indicates if the runtime is GitHub Actions")
-	pflag.Parse()
+	if len(problems) > 0 {
+		failGate(fmt.Sprintf("found %d format problem(s)", len(problems)))
+	}
+}
 
-	if outputFormat != "json" && outputFormat != "markdown" {
-		fatal(fmt.Sprintf("unknow output format %s", outputFormat))
+// compare reports the changes of the translations between the git refs given by
+// '--compare'.
+func compare() {
+	refs := strings.SplitN(compareRefs, "..", 2)
+	delta, err := translations.CompareRefs(projectDir, refs[0], refs[1], scanOptions())
+	if err != nil {
+		fatal(err)
 	}
+
+	printDelta(delta)
 }
 
-func main() {
-	valuesFiles, err := findValuesFiles(projectDir)
+// compareTranslatedBranch reports the gaps that the translations of the git ref
+// given by '--diff-against-translated-branch' fill and the ones that are left.
+func compareTranslatedBranch() {
+	delta, err := translations.CompareTranslatedBranch(projectDir, translatedRef, scanOptions())
 	if err != nil {
 		fatal(err)
 	}
 
-	localeStrings, err := findTranslatableStrings(valuesFiles)
+	printDelta(delta)
+}
+
+// compareArtifact reports the strings and locales of the values files that the APK
+// doesn't have and exits with non-zero status if there are any.
+func compareArtifact() {
+	report, err := translations.CompareArtifact(projectDir, apkPath, scanOptions())
 	if err != nil {
 		fatal(err)
 	}
 
-	defaultStrings, ok := localeStrings[defaultLocale]
-	if !ok { // shouldn't be true for valid input
-		fatal("unable to find string resources for default locale")
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
 	}
 
-	report := make([]stringResource, 0)
-	for _, str := range defaultStrings {
-		strResource := stringResource{
-			Name:            str.Name,
-			Value:           strings.TrimSpace(str.Value),
-			MissingLocales:  []string{},
-			OutdatedLocales: []string{},
-		}
+	output := mustRenderArtifactReport(renderTitle(nil), report)
+	if githubActions {
+		setGitHubActionsOutput("report", output)
+		fmt.Println()
+	}
 
-		for locale := range localeStrings {
-			if localeStr, ok := localeStrings[locale][str.Name]; !ok {
-				strResource.MissingLocales = append(strResource.MissingLocales, locale)
-			} else if localeStr.LastModified.Before(str.LastModified) {
-				strResource.OutdatedLocales = append(strResource.OutdatedLocales, locale)
-			}
-		}
+	printOutput(output)
+	if dropped := len(report.DroppedLocales) + len(report.DroppedStrings); dropped > 0 {
+		failGate(fmt.Sprintf("found %d locale(s) and %d string(s) missing in %s", len(report.DroppedLocales), len(report.DroppedStrings), apkPath))
+	}
+}
 
-		if len(strResource.MissingLocales)+len(strResource.OutdatedLocales) > 0 {
-			report = append(report, strResource)
-		}
+// inspectString prints the translations of the '--string' default string in each
+// locale.
+func inspectString() {
+	inspection, err := translations.InspectString(projectDir, inspectName, scanOptions())
+	if err != nil {
+		fatal(err)
 	}
 
-	sort.Sort(stringResources(report))
-	var output string
-	switch outputFormat {
-	case "json":
-		output = mustRenderJSON(report)
-		break
-	case "markdown":
-		output = mustRenderMarkdown(markdownTitle, report)
-		break
+	for _, warning := range inspection.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
 	}
 
+	output := mustRenderStringInspection(renderTitle(nil), inspection)
 	if githubActions {
 		setGitHubActionsOutput("report", output)
 		fmt.Println()
 	}
 
-	fmt.Println(output)
+	printOutput(output)
 }
 
-// fatal is a convenience function that calls 'fmt.Println' with 'msg' followed by an
-// 'os.Exit(1)' invocation.
-func fatal(msg interface{}) {
-	fmt.Fprintln(os.Stderr, "error:", msg)
-	os.Exit(1)
-}
+// importTranslations writes the translations of the '--import' file back to the
+// values files of the '--import-locale' locale and prints what was written.
+func importTranslations() {
+	read := translations.ReadPO
+	if filepath.Ext(importFile) == ".json" {
+		read = translations.ReadTranslationsJSON
+	}
 
-// findValuesFiles finds XML files in 'path/**/*/values*'. This function should be
-// compatible with cases where multiple resource directories are in use.
-func findValuesFiles(path string) ([]string, error) {
-	files, err := ioutil.ReadDir(path)
+	entries, err := read(importFile)
 	if err != nil {
-		return nil, errors.Wrapf(err, "unable to read directory %s", path)
+		fatal(err)
 	}
 
-	valuesFiles := make([]string, 0)
-	for _, file := range files {
-		filePath := filepath.Join(path, file.Name())
-		if isGitIgnored(path, filePath) {
-			continue
-		}
+	report, err := translations.ImportTranslations(projectDir, importLocale, entries, scanOptions())
+	if err != nil {
+		fatal(err)
+	}
 
-		if file.IsDir() {
-			moreValuesFiles, err := findValuesFiles(filePath)
-			if err != nil {
-				return nil, err
-			}
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
 
-			valuesFiles = append(valuesFiles, moreValuesFiles...)
-		} else {
-			if isValuesFile(filePath) {
-				valuesFiles = append(valuesFiles, filePath)
-			}
-		}
+	output := mustRenderImportReport(renderTitle(nil), report)
+	if githubActions {
+		setGitHubActionsOutput("report", output)
+		fmt.Println()
 	}
 
-	return valuesFiles, nil
+	printOutput(output)
 }
 
-// isValuesFile checks the prefix on the parent of the given path. It also checks
-// the file extension of the path. If the file name is equal to doNotTranslateFileName,
-// it returns false. If the prefix equals 'values' and file extension
-// equals 'xml', it returns true. False otherwise.
-func isValuesFile(path string) bool {
-	if doNotTranslateFileName == filepath.Base(path) {
-		return false
+// exportPO prints the default strings and their translations in the '--po-locale'
+// locale as a gettext PO file.
+func exportPO() {
+	catalog, err := translations.ExportPO(projectDir, poLocale, scanOptions())
+	if err != nil {
+		fatal(err)
+	}
+
+	for _, warning := range catalog.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
 	}
 
-	parent := filepath.Base(filepath.Dir(path))
-	return strings.HasPrefix(parent, "values") && strings.EqualFold(".xml", filepath.Ext(path))
+	output := renderPO(renderTitle(nil), catalog)
+	if githubActions {
+		setGitHubActionsOutput("report", output)
+		fmt.Println()
+	}
+
+	printOutput(output)
 }
 
-// findTranslatableStrings looks for '<string>' tags with '<resources>' tag as its root
-// in given files. It parses all the string tags without 'translatable="fasle"' attribute.
-// It returns a mapping of locale to their strings where locale is suffix of 'values-'.
-// If no suffix is present, i.e. 'values', defaultLocale constant is used to identify those
-// values.
-func findTranslatableStrings(files []string) (localeStringsMap, error) {
-	strResources := make(localeStringsMap, 0)
-	for _, file := range files {
-		content, err := ioutil.ReadFile(file)
-		if err != nil {
-			return nil, errors.Wrapf(err, "unable to read file at %s", file)
+// printDelta prints the warnings of the given delta and the delta itself in the
+// requested output format.
+func printDelta(delta translations.Delta) {
+	for _, warning := range delta.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+
+	output := mustRenderDelta(renderTitle(nil), delta)
+	if githubActions {
+		setGitHubActionsOutput("report", output)
+		fmt.Println()
+	}
+
+	printOutput(output)
+}
+
+// scanModules reports the translations of each Gradle module in the project
+// separately.
+func scanModules() {
+	reports, err := translations.ScanModules(projectDir, scanOptions())
+	if err != nil {
+		fatal(err)
+	}
+
+	var length, missing, outdated, affected, duplicates int
+	below, unexpected, invalid, failing := make([]string, 0), make([]string, 0), make([]string, 0), make([]string, 0)
+	for i := range reports {
+		reports[i].Report = reports[i].Report.WithLocaleAliases(localeAliases)
+		module, report := reports[i].Module, &reports[i].Report
+		sortStrings(report.Strings)
+		for _, warning := range report.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s: %s\n", module, warning)
 		}
 
-		resources := &xmlStringResources{}
-		err = xml.Unmarshal(content, resources)
-		if err != nil {
-			return nil, errors.Wrapf(err, "unable to parse XML file at %s", file)
+		length += len(report.Strings)
+		missing += report.MissingCount
+		outdated += report.OutdatedCount
+		affected += report.AffectedCount
+		duplicates += len(report.Duplicates)
+		for _, locale := range report.UnexpectedLocales {
+			unexpected = append(unexpected, fmt.Sprintf("%s/%s", module, locale))
 		}
 
-		locale := getLocaleForValuesFile(file)
-		strResCount := len(resources.Strings) + len(resources.StringArrays)
-		if _, ok := strResources[locale]; !ok && strResCount > 0 {
-			strResources[locale] = map[string]xmlStringResource{}
+		for _, locale := range report.InvalidLocales {
+			invalid = append(invalid, fmt.Sprintf("%s/%s", module, locale))
 		}
 
-		for _, str := range resources.Strings {
-			if !str.IsTranslatable() {
-				continue
+		for _, res := range report.Strings {
+			if matchesGlob(res.Name, failGlobs) {
+				failing = append(failing, fmt.Sprintf("%s/%s", module, res.Name))
 			}
+		}
 
-			start, count, err := getLineRange(content, str.Value)
-			if err == nil {
-				str.LastModified, err = getLastModifiedTime(file, start, count)
-			}
+		for _, locale := range report.LocalesBelowCoverage(minCoverage, localeMinCoverage) {
+			below = append(below, fmt.Sprintf("%s/%s (%.2f%%)", module, locale, report.LocaleCoverage[locale]))
+		}
+	}
 
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "warning:", err)
-				str.LastModified = time.Now()
+	title := renderTitle(reportTitleData(length, missing, outdated, affected))
+	output := mustRenderModuleReports(title, reports)
+	if printStats {
+		// the modules may have different locales, so none of the buckets is 'all'
+		histogram := map[int]int{}
+		for _, r := range reports {
+			for n, count := range r.Report.MissingHistogram() {
+				histogram[n] += count
 			}
-
-			strResources[locale][str.Name] = str
 		}
 
-		for _, strArr := range resources.StringArrays {
-			if !strArr.IsTranslatable() {
-				continue
-			}
+		fmt.Fprintln(os.Stderr, "missing_count:", missing)
+		fmt.Fprintln(os.Stderr, "outdated_count:", outdated)
+		fmt.Fprintln(os.Stderr, "total_affected:", affected)
+		fmt.Fprintln(os.Stderr, "missing_histogram:", formatHistogram(histogram, 0))
+	}
 
-			for i, strArrItem := range strArr.Items {
-				strArrItem.Name = fmt.Sprintf("%s[%d]", strArr.Name, i)
-				start, count, err := getLineRange(content, strArrItem.Value)
-				if err == nil {
-					strArrItem.LastModified, err = getLastModifiedTime(file, start, count)
-				}
+	if githubActions {
+		setGitHubActionsOutput("report", output)
+		setGitHubActionsOutput("missing_count", strconv.Itoa(missing))
+		setGitHubActionsOutput("outdated_count", strconv.Itoa(outdated))
+		setGitHubActionsOutput("total_affected", strconv.Itoa(affected))
+		fmt.Println()
 
-				if err != nil {
-					fmt.Fprintln(os.Stderr, "warning:", err)
-					strArrItem.LastModified = time.Now()
-				}
+		summary := output
+		if outputFormat != "markdown" {
+			summary = mustRenderModuleMarkdown(title, reports)
+		}
 
-				strResources[locale][strArrItem.Name] = strArrItem
-			}
+		if err := appendGitHubStepSummary(summary); err != nil {
+			fmt.Fprintln(os.Stderr, "warning:", err)
 		}
 	}
 
-	return strResources, nil
+	printOutput(output)
+	if strictLocales && len(invalid) > 0 {
+		failGate(fmt.Sprintf("found unrecognized locale qualifiers: %s", strings.Join(invalid, ", ")))
+	}
+
+	if failOnDuplicate && duplicates > 0 {
+		failGate(fmt.Sprintf("found %d duplicate string definition(s)", duplicates))
+	}
+
+	if strictSupported && len(unexpected) > 0 {
+		failGate(fmt.Sprintf("found unsupported locales: %s", strings.Join(unexpected, ", ")))
+	}
+
+	if len(below) > 0 {
+		failGate(fmt.Sprintf("locales below minimum coverage: %s", strings.Join(below, ", ")))
+	}
+
+	if failThreshold >= 0 && affected > failThreshold {
+		const msgFmt = "found %d string(s) with missing or outdated translations, more than the fail-threshold of %d"
+		failGate(fmt.Sprintf(msgFmt, affected, failThreshold))
+	}
+
+	if len(failing) > 0 {
+		failGate(fmt.Sprintf("found missing or outdated translations of strings matching fail-glob: %s", strings.Join(failing, ", ")))
+	}
 }
 
-// getLocaleForValuesFile returns the suffix after 'values-'. If no suffix is present,
-// e.g. 'values', it returns the defaultLocale constant.
-func getLocaleForValuesFile(path string) string {
-	parent := filepath.Base(filepath.Dir(path))
-	if strings.EqualFold(parent, "values") {
-		return defaultLocale
+// printOutput prints the given output to stdout or, if '--output-file' is set, writes
+// it to the output file. Binary XLSX output is written as is.
+func printOutput(output string) {
+	if outputFile == "" {
+		fmt.Println(output)
+		return
 	}
 
-	split := strings.SplitN(parent, "-", 2)
-	if len(split) < 2 { // edge case. shouldn't be true for valid input
-		return defaultLocale
+	if outputFormat != "xlsx" {
+		output = strings.TrimSpace(output) + "\n"
 	}
 
-	return split[1]
+	if err := ioutil.WriteFile(outputFile, []byte(output), 0644); err != nil {
+		fatal(errors.Wrapf(err, "unable to write output to %s", outputFile))
+	}
 }
 
-// isGitIgnored checks if the given path is ignored from being tracked by 'git'. 'workingDir'
-// is used provide additional to 'git' command. It returns false, if 'workingDir' is not an
-// ancestor of the given file path.
-func isGitIgnored(workingDir, file string) bool {
-	relFilePath, err := filepath.Rel(workingDir, file)
+// readFileList reads the newline-delimited list of paths in the file at the given
+// path, or stdin if the path is '-'. Blank lines are ignored.
+func readFileList(path string) ([]string, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(path)
+	}
+
 	if err != nil {
-		return false
+		return nil, errors.Wrapf(err, "unable to read the list of files from %s", path)
 	}
 
-	cmd := exec.Command("git", "check-ignore", relFilePath)
-	cmd.Dir = workingDir
-	if err := cmd.Run(); err != nil {
-		return false
+	files := make([]string, 0)
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
 	}
 
-	return true
+	return files, nil
 }
 
-// mustRenderJSON marshals the given value as JSON. It panics on encountering an error
-// while marshaling JSON.
-func mustRenderJSON(v interface{}) string {
-	content, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		panic(errors.Wrap(err, "failed to marshal content as JSON"))
+// isTerminal checks if the given file is a character device, i.e. a terminal.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printProgress prints the progress of parsing values files to stderr on a single
+// line that is overwritten on each call and cleared once all files are parsed.
+func printProgress(done, total int) {
+	if done < total {
+		fmt.Fprintf(os.Stderr, "\rparsing %d/%d files", done, total)
+	} else {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
+
+// failGate is like fatal for the failures of the gates on the report, e.g.
+// fail-threshold, except that it only prints a warning if '--exit-zero-on-report'
+// is set, so that the same invocation can report without gating.
+func failGate(msg string) {
+	if exitZero {
+		fmt.Fprintln(os.Stderr, "warning:", msg)
+		return
 	}
 
-	return string(content)
+	fatal(msg)
 }
 
-// mustRenderMarkdown tries render markdown content using on a const template.
-// If there is an error when rendering the template, it panics.
-func mustRenderMarkdown(title string, data []stringResource) string {
-	mdTemplate, err := template.New("markdown").Parse(`# {{ .title }}
-
-{{ if eq .length 0 -}}
-No missing {{- if eq .outdated_on true }} or outdated {{- end }} translations found.
-{{ else -}}
-{{ .table }}
-{{- end }}
-_Generated using [Android Translations][1] GitHub action._
-
-[1]: https://github.com/ashutoshgngwr/android-translations
-`)
-
-	var content bytes.Buffer
-	err = mdTemplate.Execute(&content, map[string]interface{}{
-		"title":       title,
-		"length":      len(data),
-		"outdated_on": outdatedLocales,
-		"table":       renderMarkdownTable(data),
-	})
+// renderTitle evaluates '--markdown-title' as a template with titleData and the
+// given data of the output, e.g. '{{ .length }} strings need attention ({{ .date }})'.
+// Titles without template actions are returned as is. It fails if the title refers
+// to data that the output doesn't have, e.g. '{{ .length }}' with '--compare'.
+func renderTitle(data map[string]interface{}) string {
+	if !strings.Contains(markdownTitle, "{{") {
+		return markdownTitle
+	}
 
+	titleTemplate, err := template.New("title").Option("missingkey=error").Parse(markdownTitle)
 	if err != nil {
-		panic(errors.Wrap(err, "unable to render data as markdown"))
+		fatal(errors.Wrapf(err, "invalid markdown-title %q", markdownTitle))
+	}
+
+	var content strings.Builder
+	if err := titleTemplate.Execute(&content, titleData(data)); err != nil {
+		fatal(errors.Wrapf(err, "unable to evaluate markdown-title %q for this output", markdownTitle))
 	}
 
 	return content.String()
 }
 
-// renderMarkdownTable pretty prints the slice of stringResource as Markdown
-// table to be used with Markdown format.
-func renderMarkdownTable(data []stringResource) string {
-	var tableContent bytes.Buffer
-	table := tablewriter.NewWriter(&tableContent)
-	table.SetBorders(tablewriter.Border{Left: true, Right: true})
-	table.SetCenterSeparator("|")
+// fatal is a convenience function that calls 'fmt.Println' with 'msg' followed by an
+// 'os.Exit(1)' invocation.
+func fatal(msg interface{}) {
+	fmt.Fprintln(os.Stderr, "error:", msg)
+	os.Exit(1)
+}
 
-	header := []string{"#", "Name", "Default Value", "Missing Locales"}
-	if outdatedLocales {
-		header = append(header, "Potentially Outdated Locales")
+// writeLocaleReports writes a report for each non-default locale to '<dir>/<locale>.<ext>'
+// where ext depends on the requested output format. Each report only contains the
+// strings that are missing or outdated for its locale.
+func writeLocaleReports(dir string, report translations.Report) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "unable to create directory %s", dir)
 	}
 
-	table.SetHeader(header)
-	for i, item := range data {
-		row := []string{
-			fmt.Sprintf("%d", 1+i),
-			fmt.Sprintf("`%s`", item.Name),
-			item.Value,
-			item.MissingLocalesString(),
-		}
+	ext := ".json"
+	if outputFormat == "markdown" || outputFormat == "diff" {
+		ext = ".md"
+	} else if outputFormat == "console" {
+		ext = ".txt"
+	} else if outputFormat == "toml" {
+		ext = ".toml"
+	} else if outputFormat == "jsonl" {
+		ext = ".jsonl"
+	}
 
-		if outdatedLocales {
-			row = append(row, item.OutdatedLocalesString())
+	for _, locale := range report.Locales {
+		localeReport := report.FilterByLocale(locale)
+		data := reportTitleData(len(localeReport.Strings), localeReport.MissingCount, localeReport.OutdatedCount, localeReport.AffectedCount)
+		output := mustRenderReport(fmt.Sprintf("%s (%s)", renderTitle(data), locale), localeReport)
+		path := filepath.Join(dir, locale+ext)
+		if err := ioutil.WriteFile(path, []byte(strings.TrimSpace(output)+"\n"), 0644); err != nil {
+			return errors.Wrapf(err, "unable to write report to %s", path)
 		}
-
-		table.Append(row)
 	}
 
-	table.Render()
-	return tableContent.String()
+	return nil
 }
 
 // setGitHubActionsOutput sets the output variable for Github Actions runtime.
-// This output can be used by other steps in a workflow.
+// This output can be used by other steps in a workflow. If the runner provides the
+// 'GITHUB_OUTPUT' file, the value is written to it as is using the multiline
+// delimiter syntax. Otherwise, it falls back to the deprecated 'set-output' command
+// which needs '%', '\r' and '\n' to be escaped.
 func setGitHubActionsOutput(key, value string) {
+	if path := os.Getenv("GITHUB_OUTPUT"); path != "" {
+		if err := appendGitHubOutput(path, key, value); err != nil {
+			fatal(err)
+		}
+
+		return
+	}
+
 	value = strings.ReplaceAll(value, "%", "%25")
 	value = strings.ReplaceAll(value, "\r", "%0D")
 	value = strings.ReplaceAll(value, "\n", "%0A")
 	fmt.Printf("::set-output name=%s::%s\n", key, value)
 }
 
-// getLastModifiedTime returns the last modified time of the given line range in the
-// given file using 'git blame'.
-func getLastModifiedTime(file string, lineStart, lineCount int) (time.Time, error) {
-	const errFmt = "unable to find last modified time, file: %q, start: %d, count: %d"
-	const cmdFmt = "git blame -p -L %d,+%d %s | grep committer-time | awk '{ print $2 }'"
-
-	var stdoutBuffer bytes.Buffer
-	command := fmt.Sprintf(cmdFmt, lineStart, lineCount, filepath.Base(file))
-	cmd := exec.Command("sh", "-c", command)
-	cmd.Dir = filepath.Dir(file)
-	cmd.Stdout = &stdoutBuffer
-	if err := cmd.Run(); err != nil {
-		return time.Time{}, errors.Wrapf(err, errFmt, file, lineStart, lineCount)
-	}
-
-	// should handle case where multiline blame returns multiple commits and thus
-	// multiple committer-time fields
-	output := strings.TrimSpace(stdoutBuffer.String())
-	var latestTimestamp int64
-	for _, timestampStr := range strings.Split(output, "\n") {
-		timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
-		if err != nil {
-			return time.Time{}, errors.Wrapf(err, errFmt, file, lineStart, lineCount)
+// appendGitHubOutput appends the given output to the 'GITHUB_OUTPUT' file at the
+// given path as 'key<<delimiter', the value and the delimiter on separate lines. The
+// delimiter is random so that the value can't end the output early.
+func appendGitHubOutput(path, key, value string) error {
+	var delimiter string
+	for delimiter == "" || strings.Contains(value, delimiter) {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return errors.Wrap(err, "unable to generate output delimiter")
+		}
+
+		delimiter = "ghadelimiter_" + hex.EncodeToString(buf)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "unable to open GitHub Actions output file")
+	}
+
+	defer file.Close()
+	if _, err := fmt.Fprintf(file, "%s<<%s\n%s\n%s\n", key, delimiter, value, delimiter); err != nil {
+		return errors.Wrap(err, "unable to write GitHub Actions output")
+	}
+
+	return nil
+}
+
+// formatHistogram formats the given histogram of the numbers of locales to the
+// numbers of strings missing in them in ascending order of the numbers of locales,
+// e.g. '12 strings in 1 locale, 3 strings in all 40 locales'. 'totalLocales' is the
+// number of locales of the report. It returns "-" if the histogram is empty.
+func formatHistogram(histogram map[int]int, totalLocales int) string {
+	if len(histogram) == 0 {
+		return "-"
+	}
+
+	buckets := make([]int, 0, len(histogram))
+	for n := range histogram {
+		buckets = append(buckets, n)
+	}
+
+	sort.Ints(buckets)
+	parts := make([]string, 0, len(buckets))
+	for _, n := range buckets {
+		strs, locales := "strings", "locales"
+		if histogram[n] == 1 {
+			strs = "string"
 		}
 
-		if timestamp > latestTimestamp {
-			latestTimestamp = timestamp
+		if n == 1 {
+			locales = "locale"
 		}
+
+		if n == totalLocales && n > 1 {
+			locales = "all " + strconv.Itoa(n) + " " + locales
+		} else {
+			locales = strconv.Itoa(n) + " " + locales
+		}
+
+		parts = append(parts, fmt.Sprintf("%d %s in %s", histogram[n], strs, locales))
 	}
 
-	return time.Unix(latestTimestamp, 0), nil
+	return strings.Join(parts, ", ")
 }
 
-// getLineRange returns the line range of the first occurrence of 'searchTerm' in
-// 'content'. 'searchTerm' can be a multiline string. It returns the following
-// positional values
-// 1. start: line number where searchTerm occurrence started
-// 2. count: total line count of the searchTerm itself.
-// 3. error: if the there was error in reading the file or find the search term
-func getLineRange(fileContent []byte, searchTerm string) (int, int, error) {
-	chunks := strings.Split(string(fileContent), searchTerm)
-	if len(chunks) < 2 {
-		const errFmt = "searchTerm: %q is not found"
-		return 0, 0, fmt.Errorf(errFmt, searchTerm)
-	}
-
-	start := 1 + strings.Count(chunks[0], "\n")
-	count := 1 + strings.Count(searchTerm, "\n")
-	return start, count, nil
+// appendGitHubStepSummary appends the given Markdown content to the job summary of
+// the current step in Github Actions runtime, i.e. the file at 'GITHUB_STEP_SUMMARY'.
+// It is a no-op if the environment variable isn't set, e.g. on older runners.
+func appendGitHubStepSummary(markdown string) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "unable to open job summary file")
+	}
+
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, strings.TrimSpace(markdown)); err != nil {
+		return errors.Wrap(err, "unable to write job summary")
+	}
+
+	return nil
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestSetFlagsFromEnv(t *testing.T) {
+	defer func(commandLine *pflag.FlagSet) { pflag.CommandLine = commandLine }(pflag.CommandLine)
+	pflag.CommandLine = pflag.NewFlagSet("android-translations", pflag.ContinueOnError)
+	projectDir := pflag.String("project-dir", ".", "")
+	outputFormat := pflag.String("output-format", "json", "")
+	minCoverage := pflag.Float64("min-coverage", 0, "")
+
+	os.Setenv("ANDROID_TRANSLATIONS_PROJECT_DIR", "from-env")
+	os.Setenv("ANDROID_TRANSLATIONS_OUTPUT_FORMAT", "markdown")
+	defer os.Unsetenv("ANDROID_TRANSLATIONS_PROJECT_DIR")
+	defer os.Unsetenv("ANDROID_TRANSLATIONS_OUTPUT_FORMAT")
+	defer func() { envFlags = map[string]string{} }()
+
+	if err := pflag.CommandLine.Parse([]string{"--project-dir", "from-flag"}); err != nil {
+		t.Fatal(err)
+	}
+
+	setFlagsFromEnv()
+	if *projectDir != "from-flag" {
+		t.Errorf("project-dir = %q, want the command line value %q", *projectDir, "from-flag")
+	}
+
+	if *outputFormat != "markdown" {
+		t.Errorf("output-format = %q, want the environment value %q", *outputFormat, "markdown")
+	}
+
+	if *minCoverage != 0 {
+		t.Errorf("min-coverage = %v, want the default value 0", *minCoverage)
+	}
+
+	if _, ok := envFlags["project-dir"]; ok {
+		t.Error("project-dir is reported as set from the environment")
+	}
+
+	if name := envFlags["output-format"]; name != "ANDROID_TRANSLATIONS_OUTPUT_FORMAT" {
+		t.Errorf("output-format is reported as set from %q, want %q", name, "ANDROID_TRANSLATIONS_OUTPUT_FORMAT")
+	}
+}
+
+func TestAppendGitHubOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "github-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "output")
+	outputs := [][2]string{
+		{"report", "| Name | Value |\r\n| --- | --- |\n| `greeting` | `Hello %1$s, you have %d new messages (100%%)` |\n"},
+		{"summary", "%25 %0A %0D are kept as is"},
+	}
+
+	for _, output := range outputs {
+		if err := appendGitHubOutput(path, output[0], output[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// parse the file like the GitHub Actions runner does
+	rest := string(content)
+	for _, output := range outputs {
+		i := strings.Index(rest, "\n")
+		header := strings.SplitN(rest[:i], "<<", 2)
+		if len(header) != 2 || header[0] != output[0] {
+			t.Fatalf("output header = %q, want %q<<delimiter", rest[:i], output[0])
+		}
+
+		rest = rest[i+1:]
+		end := strings.Index(rest, "\n"+header[1]+"\n")
+		if end < 0 {
+			t.Fatalf("output %q isn't terminated by its delimiter", output[0])
+		}
+
+		if value := rest[:end]; value != output[1] {
+			t.Errorf("output %q = %q, want %q", output[0], value, output[1])
+		}
+
+		rest = rest[end+len(header[1])+2:]
+	}
+
+	if rest != "" {
+		t.Errorf("unexpected trailing content %q", rest)
+	}
+}
@@ -0,0 +1,1300 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ashutoshgngwr/android-translations/translations"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+)
+
+// shieldsBadge declares the output structure for the shields.io endpoint schema.
+// https://shields.io/endpoint
+type shieldsBadge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// jsonSchemaVersion is the version of the JSON report structure in the envelope. It
+// must be incremented on incompatible changes of the structure.
+const jsonSchemaVersion = 1
+
+// envelopedReport declares the output structure for the JSON format if
+// '--json-envelope' is set. It wraps the strings with the metadata for the
+// consumers to handle the changes of the report structure.
+type envelopedReport struct {
+	SchemaVersion int         `json:"schema_version"`
+	ToolVersion   string      `json:"tool_version"`
+	GeneratedAt   time.Time   `json:"generated_at"`
+	Project       string      `json:"project"`   // name of the project directory
+	Resources     interface{} `json:"resources"` // strings, grouped by locale if '--group-by' is locale
+
+	// the other sections of the report, only present if they have any entries
+	Duplicates               []translations.DuplicateString    `json:"duplicates,omitempty"`
+	SuggestedNonTranslatable []translations.SuggestedString    `json:"suggested_non_translatable,omitempty"`
+	NonTranslatable          []translations.ExcludedString     `json:"non_translatable,omitempty"`
+	PunctuationWarnings      []translations.PunctuationWarning `json:"punctuation_warnings,omitempty"`
+	EscapeWarnings           []translations.EscapeWarning      `json:"escape_warnings,omitempty"`
+	LengthWarnings           []translations.LengthWarning      `json:"length_warnings,omitempty"`
+	XliffMismatches          []translations.XliffMismatch      `json:"xliff_mismatches,omitempty"`
+	Divergences              []translations.Divergence         `json:"divergences,omitempty"`
+	EmptyLocales             []string                          `json:"empty_locales,omitempty"`
+	UnexpectedLocales        []string                          `json:"unexpected_locales,omitempty"`
+}
+
+// defaultLanguage is the language declared by the 'tools:locale' attribute of the
+// default values files of the rendered report, if any.
+var defaultLanguage string
+
+// joinLocales joins the given locales using ", " separator. If '--locale-names' is
+// set, each locale is followed by its display name in parentheses. The default
+// locale is followed by its declared language, if any. It returns "-" if there are
+// no locales.
+func joinLocales(locales []string) string {
+	if len(locales) == 0 {
+		return "-"
+	}
+
+	names := make([]string, 0, len(locales))
+	for _, locale := range locales {
+		name := locale
+		if locale == translations.DefaultLocale && defaultLanguage != "" {
+			name = defaultLanguage
+		}
+
+		if localeNames {
+			name = translations.LocaleDisplayName(name)
+		}
+
+		if name != locale {
+			locale = fmt.Sprintf("%s (%s)", locale, name)
+		}
+
+		names = append(names, locale)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// tableColumn declares a column that can be rendered in the Markdown table.
+type tableColumn struct {
+	Header string
+	Value  func(index int, res translations.StringResource) string
+}
+
+// tableColumns maps the column names accepted by '--columns' flag to their
+// table column definitions.
+var tableColumns = map[string]tableColumn{
+	"index": {"#", func(i int, res translations.StringResource) string { return fmt.Sprintf("%d", 1+i) }},
+	"name": {"Name", func(i int, res translations.StringResource) string {
+		if res.SourceURL != "" {
+			return fmt.Sprintf("[`%s`](%s)", res.Name, res.SourceURL)
+		}
+
+		return fmt.Sprintf("`%s`", res.Name)
+	}},
+	"value": {"Default Value", func(i int, res translations.StringResource) string { return previewValue(res.Value) }},
+	"type":  {"Type", func(i int, res translations.StringResource) string { return res.Type }},
+	"missing": {"Missing Locales", func(i int, res translations.StringResource) string {
+		return joinLocales(res.MissingLocales)
+	}},
+	"outdated": {"Potentially Outdated Locales", func(i int, res translations.StringResource) string {
+		return joinLocales(res.OutdatedLocales)
+	}},
+	"identical": {"Identical Locales", func(i int, res translations.StringResource) string {
+		return joinLocales(res.IdenticalLocales)
+	}},
+	"file":    {"File", func(i int, res translations.StringResource) string { return res.File }},
+	"line":    {"Line", func(i int, res translations.StringResource) string { return fmt.Sprintf("%d", res.Line) }},
+	"comment": {"Comment", func(i int, res translations.StringResource) string { return res.Comment }},
+	"hint":    {"Translator Hint", func(i int, res translations.StringResource) string { return res.TranslatorHint }},
+	"author":  {"Last Modified By", func(i int, res translations.StringResource) string { return res.LastModifiedBy }},
+	"staleness": {"Staleness", func(i int, res translations.StringResource) string {
+		if len(res.OutdatedLocalesLag) == 0 {
+			return "-"
+		}
+
+		return humanizeDuration(res.MaxLag()) + " behind"
+	}},
+	"commit": {"Outdating Commit", func(i int, res translations.StringResource) string {
+		if res.OutdatingCommit == nil {
+			return "-"
+		}
+
+		return fmt.Sprintf("`%.7s` %s", res.OutdatingCommit.Hash, res.OutdatingCommit.Summary)
+	}},
+}
+
+// previewValue returns the given value as is unless '--max-value-preview' is set.
+// Otherwise, it joins the lines of the value with spaces to keep the table rows
+// intact, and truncates it to that many characters with an ellipsis.
+func previewValue(value string) string {
+	if maxPreview <= 0 {
+		return value
+	}
+
+	value = strings.Join(strings.Fields(value), " ")
+	if runes := []rune(value); len(runes) > maxPreview {
+		return string(runes[:maxPreview]) + "…"
+	}
+
+	return value
+}
+
+// humanizeDuration returns the given duration in its largest whole unit, e.g.
+// '3 months' or '1 day'. Months and years are approximated as 30 and 365 days.
+func humanizeDuration(d time.Duration) string {
+	const day = 24 * time.Hour
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * day},
+		{"month", 30 * day},
+		{"week", 7 * day},
+		{"day", day},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	for _, unit := range units {
+		if n := int64(d / unit.size); n == 1 {
+			return "1 " + unit.name
+		} else if n > 1 {
+			return fmt.Sprintf("%d %ss", n, unit.name)
+		}
+	}
+
+	return "less than a minute"
+}
+
+// mustRenderJSON marshals the given value as JSON. It is pretty-printed with two-space
+// indent unless '--json-compact' is set. It panics on encountering an error while
+// marshaling JSON.
+func mustRenderJSON(v interface{}) string {
+	if jsonCompact {
+		return mustRenderJSONLine(v)
+	}
+
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panic(errors.Wrap(err, "failed to marshal content as JSON"))
+	}
+
+	return string(content)
+}
+
+// tomlReport declares the output structure for the TOML format. TOML documents must
+// be tables, so the strings and the other sections of the report are rendered as
+// arrays of tables.
+type tomlReport struct {
+	Strings                  []translations.StringResource     `toml:"strings"`
+	Duplicates               []translations.DuplicateString    `toml:"duplicates,omitempty"`
+	SuggestedNonTranslatable []translations.SuggestedString    `toml:"suggested_non_translatable,omitempty"`
+	NonTranslatable          []translations.ExcludedString     `toml:"non_translatable,omitempty"`
+	PunctuationWarnings      []translations.PunctuationWarning `toml:"punctuation_warnings,omitempty"`
+	EscapeWarnings           []translations.EscapeWarning      `toml:"escape_warnings,omitempty"`
+	LengthWarnings           []translations.LengthWarning      `toml:"length_warnings,omitempty"`
+	XliffMismatches          []translations.XliffMismatch      `toml:"xliff_mismatches,omitempty"`
+	Divergences              []translations.Divergence         `toml:"divergences,omitempty"`
+	EmptyLocales             []string                          `toml:"empty_locales,omitempty"`
+	UnexpectedLocales        []string                          `toml:"unexpected_locales,omitempty"`
+}
+
+// jsonStream writes values as the elements of a JSON array, or as JSON Lines if
+// 'lines' is set, as soon as they are written. The array is compact if 'compact' is
+// set. The output is identical to that of mustRenderJSON, or mustRenderJSONLines,
+// for a slice of the same values.
+type jsonStream struct {
+	w       io.Writer
+	lines   bool
+	compact bool
+	length  int
+}
+
+// mustWrite writes the given value as the next element of the array, or as the
+// next line. It panics on encountering an error while marshaling JSON.
+func (s *jsonStream) mustWrite(v interface{}) {
+	s.length++
+	if s.lines {
+		io.WriteString(s.w, mustRenderJSONLine(v)+"\n")
+		return
+	}
+
+	if s.compact {
+		separator := ","
+		if s.length == 1 {
+			separator = "["
+		}
+
+		io.WriteString(s.w, separator+mustRenderJSONLine(v))
+		return
+	}
+
+	content, err := json.MarshalIndent(v, "  ", "  ")
+	if err != nil {
+		panic(errors.Wrap(err, "failed to marshal content as JSON"))
+	}
+
+	separator := ",\n  "
+	if s.length == 1 {
+		separator = "[\n  "
+	}
+
+	io.WriteString(s.w, separator)
+	s.w.Write(content)
+}
+
+// close ends the array. It is a no-op for JSON Lines.
+func (s *jsonStream) close() {
+	if s.lines {
+		return
+	}
+
+	if s.length == 0 {
+		io.WriteString(s.w, "[]\n")
+	} else if s.compact {
+		io.WriteString(s.w, "]\n")
+	} else {
+		io.WriteString(s.w, "\n]\n")
+	}
+}
+
+// mustRenderJSONLine marshals the given value as compact JSON on a single line. It
+// panics on encountering an error while marshaling JSON.
+func mustRenderJSONLine(v interface{}) string {
+	content, err := json.Marshal(v)
+	if err != nil {
+		panic(errors.Wrap(err, "failed to marshal content as JSON"))
+	}
+
+	return string(content)
+}
+
+// mustRenderJSONLines marshals the given strings as JSON Lines, i.e. one compact
+// JSON object per line. It panics on encountering an error while marshaling JSON.
+func mustRenderJSONLines(strs []translations.StringResource) string {
+	lines := make([]string, 0, len(strs))
+	for _, res := range strs {
+		lines = append(lines, mustRenderJSONLine(res))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// consoleColors indicates if the console output uses ANSI colors. It is set when
+// stdout is a terminal, unless 'NO_COLOR' environment variable is set.
+var consoleColors bool
+
+// consoleHeaders overrides the headers of the table columns that are too long for
+// the console table.
+var consoleHeaders = map[string]string{
+	"missing":  "Missing",
+	"outdated": "Outdated",
+}
+
+// renderConsole renders the given report as a compact table of the selected
+// columns for reading in a terminal, with the missing locales in red and the outdated locales in yellow if
+// consoleColors is set.
+func renderConsole(report translations.Report) string {
+	if len(report.Strings) == 0 {
+		if outdatedLocales {
+			return "No missing or outdated translations found."
+		}
+
+		return "No missing translations found."
+	}
+
+	var content bytes.Buffer
+	table := tablewriter.NewWriter(&content)
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	table.SetHeaderLine(false)
+	table.SetColumnSeparator("")
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if name, ok := consoleHeaders[column]; ok {
+			header = append(header, name)
+		} else {
+			header = append(header, tableColumns[column].Header)
+		}
+	}
+
+	// colors the locales of the given kind, unless there aren't any
+	color := func(locales []string, color int) tablewriter.Colors {
+		if len(locales) == 0 {
+			return tablewriter.Colors{}
+		}
+
+		return tablewriter.Colors{color}
+	}
+
+	table.SetHeader(header)
+	for i, res := range report.Strings {
+		row := make([]string, 0, len(columns))
+		colors := make([]tablewriter.Colors, 0, len(columns))
+		for _, column := range columns {
+			switch column {
+			case "name": // without the Markdown code span and link
+				row = append(row, res.Name)
+				colors = append(colors, tablewriter.Colors{})
+			case "missing":
+				row = append(row, joinLocales(res.MissingLocales))
+				colors = append(colors, color(res.MissingLocales, tablewriter.FgRedColor))
+			case "outdated":
+				row = append(row, joinLocales(res.OutdatedLocales))
+				colors = append(colors, color(res.OutdatedLocales, tablewriter.FgYellowColor))
+			default:
+				row = append(row, tableColumns[column].Value(i, res))
+				colors = append(colors, tablewriter.Colors{})
+			}
+		}
+
+		if consoleColors {
+			table.Rich(row, colors)
+		} else {
+			table.Append(row)
+		}
+	}
+
+	table.Render()
+	fmt.Fprintf(&content, "\n%d missing, %d outdated, %d affected string(s)\n",
+		report.MissingCount, report.OutdatedCount, report.AffectedCount)
+	return content.String()
+}
+
+// withSelectedFields returns the given strings without their files, lines and
+// identical locales unless the 'file', 'line' and 'identical' columns are selected
+// with '--columns', so that the JSON, JSON Lines and TOML records only have these
+// fields on request.
+func withSelectedFields(strs []translations.StringResource) []translations.StringResource {
+	selected := make([]translations.StringResource, 0, len(strs))
+	for _, res := range strs {
+		selected = append(selected, withSelectedField(res))
+	}
+
+	return selected
+}
+
+// withSelectedField is like withSelectedFields for a single string.
+func withSelectedField(res translations.StringResource) translations.StringResource {
+	if !hasColumn("file") {
+		res.File = ""
+	}
+
+	if !hasColumn("line") {
+		res.Line = 0
+	}
+
+	if !hasColumn("identical") {
+		res.IdenticalLocales = nil
+	}
+
+	return res
+}
+
+// hasColumn checks if the given column is selected with '--columns', or by default.
+func hasColumn(name string) bool {
+	for _, column := range columns {
+		if column == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mustRenderTOML marshals the strings of the given report as an array of
+// '[[strings]]' tables in TOML, followed by its other sections that have any
+// entries, e.g. the duplicate strings as '[[duplicates]]' tables. It panics on encountering an error while marshaling TOML.
+func mustRenderTOML(report translations.Report) string {
+	var content bytes.Buffer
+	if err := toml.NewEncoder(&content).Encode(tomlReport{
+		Strings:                  withSelectedFields(report.Strings),
+		Duplicates:               report.Duplicates,
+		SuggestedNonTranslatable: report.SuggestedNonTranslatable,
+		NonTranslatable:          report.NonTranslatable,
+		PunctuationWarnings:      report.PunctuationWarnings,
+		EscapeWarnings:           report.EscapeWarnings,
+		LengthWarnings:           report.LengthWarnings,
+		XliffMismatches:          report.XliffMismatches,
+		Divergences:              report.Divergences,
+		EmptyLocales:             report.EmptyLocales,
+		UnexpectedLocales:        report.UnexpectedLocales,
+	}); err != nil {
+		panic(errors.Wrap(err, "failed to marshal content as TOML"))
+	}
+
+	return content.String()
+}
+
+// mustRenderReport renders the given report in the requested output format. It
+// panics on encountering an error while rendering.
+func mustRenderReport(title string, report translations.Report) string {
+	switch outputFormat {
+	case "markdown":
+		return mustRenderMarkdown(title, report)
+	case "console":
+		return renderConsole(report)
+	case "badge":
+		return mustRenderBadge(report.Coverage)
+	case "diff":
+		return mustRenderDiff(title, report.OutdatedDiffs)
+	case "toml":
+		return mustRenderTOML(report)
+	case "jsonl":
+		return mustRenderJSONLines(withSelectedFields(report.Strings))
+	case "xlsx":
+		return mustRenderXLSX(report)
+	default:
+		if jsonEnvelope {
+			return mustRenderJSON(envelopedReport{
+				SchemaVersion: jsonSchemaVersion,
+				ToolVersion:   version,
+				GeneratedAt:   now.UTC().Truncate(time.Second),
+				Project:       projectName(),
+				Resources:     groupStrings(report),
+
+				Duplicates:               report.Duplicates,
+				SuggestedNonTranslatable: report.SuggestedNonTranslatable,
+				NonTranslatable:          report.NonTranslatable,
+				PunctuationWarnings:      report.PunctuationWarnings,
+				EscapeWarnings:           report.EscapeWarnings,
+				LengthWarnings:           report.LengthWarnings,
+				XliffMismatches:          report.XliffMismatches,
+				Divergences:              report.Divergences,
+				EmptyLocales:             report.EmptyLocales,
+				UnexpectedLocales:        report.UnexpectedLocales,
+			})
+		}
+
+		return mustRenderJSON(groupStrings(report))
+	}
+}
+
+// groupStrings returns the strings of the given report as is if '--group-by' is
+// string. If it is locale, it returns the strings missing or outdated in each
+// locale, mapped by the locales, with their locales limited to that locale.
+func groupStrings(report translations.Report) interface{} {
+	if groupBy != "locale" {
+		return withSelectedFields(report.Strings)
+	}
+
+	grouped := map[string][]translations.StringResource{}
+	for _, locale := range report.Locales {
+		if strs := report.FilterByLocale(locale).Strings; len(strs) > 0 {
+			grouped[locale] = withSelectedFields(strs)
+		}
+	}
+
+	return grouped
+}
+
+// projectName returns the name of the project directory, or the project directory
+// as is if its absolute path can't be found.
+func projectName() string {
+	dir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return projectDir
+	}
+
+	return filepath.Base(dir)
+}
+
+// renderPO renders the given catalog as a gettext PO file, with the name of each
+// string as the context of its message.
+func renderPO(title string, catalog translations.POCatalog) string {
+	var content strings.Builder
+	fmt.Fprintf(&content, "# %s\n", title)
+	fmt.Fprintf(&content, "msgid \"\"\nmsgstr \"\"\n")
+	fmt.Fprintf(&content, "%s\n", poQuote("Content-Type: text/plain; charset=UTF-8\n"))
+	fmt.Fprintf(&content, "%s\n", poQuote("Language: "+catalog.Language+"\n"))
+	if catalog.PluralForms != "" {
+		fmt.Fprintf(&content, "%s\n", poQuote("Plural-Forms: "+catalog.PluralForms+"\n"))
+	}
+	for _, entry := range catalog.Entries {
+		content.WriteString("\n")
+		if entry.Comment != "" {
+			fmt.Fprintf(&content, "#. %s\n", strings.Join(strings.Fields(entry.Comment), " "))
+		}
+
+		fmt.Fprintf(&content, "#: %s:%d\n", entry.File, entry.Line)
+		if entry.Fuzzy {
+			content.WriteString("#, fuzzy\n")
+		}
+
+		fmt.Fprintf(&content, "msgctxt %s\n", poQuote(entry.Context))
+		fmt.Fprintf(&content, "msgid %s\n", poQuote(entry.ID))
+		if entry.IDPlural == "" {
+			fmt.Fprintf(&content, "msgstr %s\n", poQuote(entry.Str[0]))
+			continue
+		}
+
+		fmt.Fprintf(&content, "msgid_plural %s\n", poQuote(entry.IDPlural))
+		for i, str := range entry.Str {
+			fmt.Fprintf(&content, "msgstr[%d] %s\n", i, poQuote(str))
+		}
+	}
+
+	return content.String()
+}
+
+// poQuote returns the given value as a double-quoted PO string with the C escape
+// sequences that gettext understands.
+func poQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// mustRenderBadge renders the given coverage percentage as JSON in the shields.io
+// endpoint schema. It panics on encountering an error while marshaling JSON.
+func mustRenderBadge(coverage float64) string {
+	color := "red"
+	if coverage >= badgeGreenAt {
+		color = "green"
+	} else if coverage >= badgeYellowAt {
+		color = "yellow"
+	}
+
+	return mustRenderJSON(shieldsBadge{
+		SchemaVersion: 1,
+		Label:         "translations",
+		Message:       fmt.Sprintf("%d%%", int(math.Floor(coverage))),
+		Color:         color,
+	})
+}
+
+// defaultFooter is the footer of the Markdown content unless '--footer' or
+// '--no-footer' is set.
+const defaultFooter = `_Generated using [Android Translations][1] GitHub action._
+
+[1]: https://github.com/ashutoshgngwr/android-translations`
+
+// markdownFooter returns the footer of the Markdown content followed by a newline,
+// or an empty string if '--no-footer' is set.
+func markdownFooter() string {
+	if noFooter {
+		return ""
+	}
+
+	if footer != "" {
+		return footer + "\n"
+	}
+
+	return defaultFooter + "\n"
+}
+
+// mustRenderMarkdown tries render markdown content using on a const template.
+// If there is an error when rendering the template, it panics.
+func mustRenderMarkdown(title string, report translations.Report) string {
+	funcs := template.FuncMap{"joinLocales": joinLocales}
+	mdTemplate, err := template.New("markdown").Funcs(funcs).Parse(`# {{ .title }}
+
+{{ if eq .length 0 -}}
+{{ if .success_message -}}
+{{ .success_message }}
+{{- else -}}
+No missing {{- if eq .outdated_on true }} or outdated {{- end }} translations found.
+{{- end }}
+{{ else -}}
+{{ .table }}
+{{- if gt .overflow 0 }}
+_...and {{ .overflow }} more._
+{{ end }}
+{{- end }}
+{{ if .classified -}}
+## Missing Strings by Severity
+
+{{ if gt (len .must_have) 0 -}}
+Must-have strings, which block the release:
+
+{{ range .must_have -}}
+- ` + "`{{ .Name }}`" + ` in {{ joinLocales .MissingLocales }}
+{{ end }}
+{{ end -}}
+{{ if gt (len .fallback_safe) 0 -}}
+Safe fallbacks, which show the default value when missing:
+
+{{ range .fallback_safe -}}
+- ` + "`{{ .Name }}`" + ` in {{ joinLocales .MissingLocales }}
+{{ end }}
+{{ end -}}
+{{ end -}}
+{{ if gt (len .duplicates) 0 -}}
+## Duplicate Strings
+
+{{ range .duplicates -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale, ` + "`{{ .File }}`" + ` (first defined in ` + "`{{ .FirstFile }}`" + `)
+{{ end }}
+{{ end -}}
+{{ if gt (len .suggested) 0 -}}
+## Suggested Non-Translatable Strings
+
+{{ range .suggested -}}
+- ` + "`{{ .Name }}`" + ` (` + "`{{ .Value }}`" + `) in ` + "`{{ .File }}`" + `
+{{ end }}
+{{ end -}}
+{{ if gt (len .non_translatable) 0 -}}
+## Non-Translatable Strings
+
+{{ range .non_translatable -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .File }}`" + ` {{ if eq .Reason "file" }}(excluded by file name){{ else }}(translatable="false"){{ end }}
+{{ end }}
+{{ end -}}
+{{ if .divergences -}}
+## Divergences
+
+{{ .divergences }}
+{{ end -}}
+{{ if gt (len .escapes) 0 -}}
+## Escape Warnings
+
+{{ range .escapes -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale: {{ .Message }}
+{{ end }}
+{{ end -}}
+{{ if gt (len .xliff) 0 -}}
+## Placeholder Mismatches
+
+{{ range .xliff -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale:
+{{- with .MissingIDs }} missing {{ range $i, $id := . }}{{ if $i }}, {{ end }}` + "`{{ $id }}`" + `{{ end }}{{ end }}
+{{- if and .MissingIDs .ExtraIDs }};{{ end }}
+{{- with .ExtraIDs }} unexpected {{ range $i, $id := . }}{{ if $i }}, {{ end }}` + "`{{ $id }}`" + `{{ end }}{{ end }}
+{{ end }}
+{{ end -}}
+{{ if gt (len .punctuation) 0 -}}
+## Punctuation Warnings
+
+{{ range .punctuation -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale: {{ .Message }}
+{{ end }}
+{{ end -}}
+{{ if gt (len .lengths) 0 -}}
+## Length Warnings
+
+{{ range .lengths -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale: {{ .Length }} characters, {{ printf "%.2f" .Ratio }}x the default string ({{ .DefaultLength }})
+{{ end }}
+{{ end -}}
+{{ if or (gt (len .unexpected) 0) (gt (len .totally_missing) 0) -}}
+## Unsupported Locales
+
+{{ range .unexpected -}}
+- ` + "`{{ . }}`" + ` has strings but isn't a supported locale
+{{ end -}}
+{{ range .totally_missing -}}
+- ` + "`{{ . }}`" + ` is a supported locale without any strings
+{{ end }}
+{{ end -}}
+{{ if gt (len .empty) 0 -}}
+## Empty Locales
+
+{{ range .empty -}}
+- ` + "`{{ . }}`" + ` has values files but no translatable strings
+{{ end }}
+{{ end -}}
+{{ .footer }}`)
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to parse markdown template"))
+	}
+
+	rows := report.Strings
+	if maxRows > 0 && len(rows) > maxRows {
+		rows = rows[:maxRows]
+	}
+
+	table := renderMarkdownTable(rows)
+	overflow := len(report.Strings) - len(rows)
+	if groupBy == "locale" {
+		table, overflow = renderLocaleSections(report), 0
+	}
+
+	mustHave, safe := classifyMissing(report.Strings)
+	data := map[string]interface{}{
+		"date":        now.Format("2006-01-02"),
+		"footer":      markdownFooter(),
+		"length":      len(report.Strings),
+		"outdated_on": outdatedLocales,
+		"table":       table,
+		"overflow":    overflow,
+		"duplicates":  report.Duplicates,
+		"suggested":   report.SuggestedNonTranslatable,
+		"punctuation": report.PunctuationWarnings,
+		"lengths":     report.LengthWarnings,
+		"escapes":     report.EscapeWarnings,
+		"xliff":       report.XliffMismatches,
+		"divergences": renderDivergencesTable(report.Divergences),
+
+		"non_translatable": report.NonTranslatable,
+		"unexpected":       report.UnexpectedLocales,
+		"totally_missing":  report.TotallyMissingLocales,
+		"empty":            report.EmptyLocales,
+		"success_message":  successMessage,
+		"classified":       len(fallbackSafe) > 0 && len(mustHave)+len(safe) > 0,
+		"must_have":        mustHave,
+		"fallback_safe":    safe,
+	}
+
+	data["title"] = title
+	var content bytes.Buffer
+	if err := mdTemplate.Execute(&content, data); err != nil {
+		panic(errors.Wrap(err, "unable to render data as markdown"))
+	}
+
+	return content.String()
+}
+
+// titleData returns the data that '--markdown-title' is evaluated with by all
+// outputs, i.e. the current date, the project name and the version of the tool,
+// along with the given data of the output, e.g. the counts of a report.
+func titleData(data map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{
+		"date":    now.Format("2006-01-02"),
+		"project": projectName(),
+		"version": version,
+	}
+
+	for key, value := range data {
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// reportTitleData returns the data of a report for evaluating '--markdown-title',
+// i.e. the number of reported strings and the counts of the affected strings.
+func reportTitleData(length, missing, outdated, affected int) map[string]interface{} {
+	return map[string]interface{}{
+		"length":         length,
+		"missing_count":  missing,
+		"outdated_count": outdated,
+		"affected_count": affected,
+	}
+}
+
+// classifyMissing splits the given strings that are missing in any locale into the
+// must-have ones and the ones matching '--fallback-safe' patterns, which fall back
+// to the default language at runtime, e.g. with the language splits of Android App
+// Bundles.
+func classifyMissing(strs []translations.StringResource) ([]translations.StringResource, []translations.StringResource) {
+	mustHave := make([]translations.StringResource, 0)
+	safe := make([]translations.StringResource, 0)
+	for _, res := range strs {
+		if len(res.MissingLocales) == 0 {
+			continue
+		}
+
+		if matchesGlob(res.Name, fallbackSafe) {
+			safe = append(safe, res)
+		} else {
+			mustHave = append(mustHave, res)
+		}
+	}
+
+	return mustHave, safe
+}
+
+// mustRenderDiff renders the given outdated diffs as unified-diff-like blocks in
+// Markdown. If there is an error when rendering the template, it panics.
+func mustRenderDiff(title string, diffs []translations.OutdatedDiff) string {
+	funcs := template.FuncMap{
+		"prefixLines": func(prefix, value string) string {
+			return prefix + strings.ReplaceAll(value, "\n", "\n"+prefix)
+		},
+	}
+
+	diffTemplate, err := template.New("diff").Funcs(funcs).Parse(`# {{ .title }}
+
+{{ if eq (len .diffs) 0 -}}
+No outdated translations found.
+
+{{ end -}}
+{{ range .diffs -}}
+## ` + "`{{ .Name }}`" + ` ({{ .Locale }})
+
+` + "```diff" + `
+{{ if .PreviousValue -}}
+{{ prefixLines "- " .PreviousValue }}
+{{ end -}}
+{{ prefixLines "+ " .CurrentValue }}
+` + "```" + `
+{{ if not .PreviousValue }}
+_Previous default value is unknown._
+{{ end }}
+**Translation:** {{ .Translation }}
+
+{{ end -}}
+{{ .footer }}`)
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to parse diff template"))
+	}
+
+	var content bytes.Buffer
+	err = diffTemplate.Execute(&content, map[string]interface{}{
+		"title":  title,
+		"footer": markdownFooter(),
+		"diffs":  diffs,
+	})
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to render data as diff"))
+	}
+
+	return content.String()
+}
+
+// moduleStrings declares the JSON output structure for the strings of a module.
+type moduleStrings struct {
+	Module  string      `json:"module"`
+	Strings interface{} `json:"strings"` // grouped by locale if '--group-by' is locale
+}
+
+// mustRenderModuleReports renders the given module reports in the requested output
+// format, i.e. JSON or Markdown. See mustRenderModuleMarkdown. It panics on
+// encountering an error while rendering.
+func mustRenderModuleReports(title string, reports []translations.ModuleReport) string {
+	if outputFormat != "markdown" {
+		modules := make([]moduleStrings, 0, len(reports))
+		for _, r := range reports {
+			modules = append(modules, moduleStrings{Module: r.Module, Strings: groupStrings(r.Report)})
+		}
+
+		return mustRenderJSON(modules)
+	}
+
+	return mustRenderModuleMarkdown(title, reports)
+}
+
+// mustRenderModuleMarkdown renders the given module reports in Markdown, where the
+// report of each module is titled with the module path. It panics on encountering
+// an error while rendering.
+func mustRenderModuleMarkdown(title string, reports []translations.ModuleReport) string {
+	if len(reports) == 0 {
+		return fmt.Sprintf("# %s\n\nNo modules with translations found.\n", title)
+	}
+
+	outputs := make([]string, 0, len(reports))
+	for _, r := range reports {
+		outputs = append(outputs, mustRenderMarkdown(fmt.Sprintf("%s: %s", title, r.Module), r.Report))
+	}
+
+	return strings.Join(outputs, "\n")
+}
+
+// mustRenderDelta renders the given delta between two git refs in the requested
+// output format, i.e. JSON or Markdown. It panics on encountering an error while
+// rendering.
+func mustRenderDelta(title string, delta translations.Delta) string {
+	if outputFormat != "markdown" {
+		return mustRenderJSON(delta)
+	}
+
+	deltaTemplate, err := template.New("delta").Parse(`# {{ .title }}
+
+Changes of the translations from ` + "`{{ .delta.OldRef }}`" + ` to ` + "`{{ .delta.NewRef }}`" + `.
+
+## Coverage
+
+{{ .table }}
+{{ if gt (len .delta.NewlyTranslated) 0 -}}
+## Newly Translated
+
+{{ range .delta.NewlyTranslated -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale
+{{ end }}
+{{ end -}}
+{{ if gt (len .delta.NewlyMissing) 0 -}}
+## Newly Missing
+
+{{ range .delta.NewlyMissing -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale
+{{ end }}
+{{ end -}}
+{{ if gt (len .delta.StillMissing) 0 -}}
+## Still Missing
+
+{{ range .delta.StillMissing -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale
+{{ end }}
+{{ end -}}
+{{ .footer }}`)
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to parse delta template"))
+	}
+
+	rows := make([][]string, 0, len(delta.Locales))
+	for _, l := range delta.Locales {
+		rows = append(rows, []string{
+			joinLocales([]string{l.Locale}),
+			fmt.Sprintf("%.2f%%", l.OldCoverage),
+			fmt.Sprintf("%.2f%%", l.NewCoverage),
+			fmt.Sprintf("%+.2f%%", l.Change),
+		})
+	}
+
+	var content bytes.Buffer
+	err = deltaTemplate.Execute(&content, map[string]interface{}{
+		"title":  title,
+		"footer": markdownFooter(),
+		"delta":  delta,
+		"table":  renderTable([]string{"Locale", delta.OldRef, delta.NewRef, "Change"}, rows),
+	})
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to render data as delta"))
+	}
+
+	return content.String()
+}
+
+// mustRenderGapDelta renders the given changes of the gaps since a previous report
+// in the requested output format, i.e. JSON or Markdown. It panics on encountering
+// an error while rendering.
+func mustRenderGapDelta(title string, delta translations.GapDelta) string {
+	if outputFormat != "markdown" {
+		return mustRenderJSON(delta)
+	}
+
+	gapDeltaTemplate, err := template.New("gap-delta").Parse(`# {{ .title }}
+
+Since the previous report, {{ .resolved }} string(s) were translated or updated and {{ .regressed }} string(s) regressed.
+
+{{ if gt (len .delta.NewlyMissing) 0 -}}
+## Newly Missing
+
+{{ range .delta.NewlyMissing -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale
+{{ end }}
+{{ end -}}
+{{ if gt (len .delta.NewlyOutdated) 0 -}}
+## Newly Outdated
+
+{{ range .delta.NewlyOutdated -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale
+{{ end }}
+{{ end -}}
+{{ if gt (len .delta.Resolved) 0 -}}
+## Resolved
+
+{{ range .delta.Resolved -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale, no longer {{ .Kind }}
+{{ end }}
+{{ end -}}
+{{ .footer }}`)
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to parse gap delta template"))
+	}
+
+	// count the strings rather than the gaps for the summary
+	countNames := func(gapLists ...[]translations.Gap) int {
+		names := map[string]bool{}
+		for _, gaps := range gapLists {
+			for _, gap := range gaps {
+				names[gap.Name] = true
+			}
+		}
+
+		return len(names)
+	}
+
+	var content bytes.Buffer
+	err = gapDeltaTemplate.Execute(&content, map[string]interface{}{
+		"title":     title,
+		"footer":    markdownFooter(),
+		"delta":     delta,
+		"resolved":  countNames(delta.Resolved),
+		"regressed": countNames(delta.NewlyMissing, delta.NewlyOutdated),
+	})
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to render data as gap delta"))
+	}
+
+	return content.String()
+}
+
+// mustRenderStringInspection renders the given translations of a single string in
+// the requested output format, i.e. JSON or Markdown. It panics on encountering an
+// error while rendering.
+func mustRenderStringInspection(title string, inspection translations.StringInspection) string {
+	if outputFormat != "markdown" {
+		return mustRenderJSON(inspection)
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	header := []string{"Locale", "Status", "Value", "Last Modified", "Last Modified By"}
+	rows := make([][]string, 0, len(inspection.Locales))
+	for _, status := range inspection.Locales {
+		value := "-"
+		if status.File != "" {
+			value = previewValue(status.Value)
+		}
+
+		rows = append(rows, []string{status.Locale, status.Status, value, formatTime(status.LastModified), status.LastModifiedBy})
+	}
+
+	inspectionTemplate, err := template.New("inspection").Parse(`# {{ .title }}
+
+Translations of ` + "`{{ .inspection.Name }}`" + ` ({{ .inspection.Type }}) defined at ` + "`{{ .inspection.File }}:{{ .inspection.Line }}`" + `.
+
+- Default value: {{ .inspection.Value }}
+- Last modified: {{ .last_modified }}{{ if .inspection.LastModifiedBy }} by {{ .inspection.LastModifiedBy }}{{ end }}
+
+{{ if gt (len .inspection.Locales) 0 -}}
+{{ .table }}
+{{- else -}}
+There are no locales other than the default locale.
+{{- end }}
+{{ .footer }}`)
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to parse string inspection template"))
+	}
+
+	var content bytes.Buffer
+	err = inspectionTemplate.Execute(&content, map[string]interface{}{
+		"title":         title,
+		"footer":        markdownFooter(),
+		"inspection":    inspection,
+		"last_modified": formatTime(inspection.LastModified),
+		"table":         renderTable(header, rows),
+	})
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to render string inspection as markdown"))
+	}
+
+	return content.String()
+}
+
+// mustRenderImportReport renders the given imported translations in the requested
+// output format, i.e. JSON or Markdown. It panics on encountering an error while
+// rendering.
+func mustRenderImportReport(title string, report translations.ImportReport) string {
+	if outputFormat != "markdown" {
+		return mustRenderJSON(report)
+	}
+
+	importTemplate, err := template.New("import").Parse(`# {{ .title }}
+
+Imported {{ len .report.Updated }} updated and {{ len .report.Added }} new translation(s) of ` + "`{{ .report.Locale }}`" + ` locale.
+
+{{ if gt (len .report.Files) 0 -}}
+## Written Files
+
+{{ range .report.Files -}}
+- ` + "`{{ . }}`" + `
+{{ end }}
+{{ end -}}
+{{ if gt (len .report.Skipped) 0 -}}
+## Skipped Translations
+
+{{ range .report.Skipped -}}
+- ` + "`{{ . }}`" + `
+{{ end }}
+{{ end -}}
+{{ .footer }}`)
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to parse import template"))
+	}
+
+	var content bytes.Buffer
+	err = importTemplate.Execute(&content, map[string]interface{}{
+		"title":  title,
+		"footer": markdownFooter(),
+		"report": report,
+	})
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to render import report as markdown"))
+	}
+
+	return content.String()
+}
+
+// mustRenderArtifactReport renders the given comparison of the values files with an
+// APK in the requested output format, i.e. JSON or Markdown. It panics on
+// encountering an error while rendering.
+func mustRenderArtifactReport(title string, report translations.ArtifactReport) string {
+	if outputFormat != "markdown" {
+		return mustRenderJSON(report)
+	}
+
+	artifactTemplate, err := template.New("artifact").Parse(`# {{ .title }}
+
+Strings of the values files that ` + "`{{ .report.Artifact }}`" + ` doesn't have.
+
+{{ if and (eq (len .report.DroppedLocales) 0) (eq (len .report.DroppedStrings) 0) -}}
+All strings of the values files are in the APK.
+
+{{ end -}}
+{{ if gt (len .report.DroppedLocales) 0 -}}
+## Missing Locales
+
+{{ range .report.DroppedLocales -}}
+- ` + "`{{ . }}`" + `
+{{ end }}
+{{ end -}}
+{{ if gt (len .report.DroppedStrings) 0 -}}
+## Missing Strings
+
+{{ range .report.DroppedStrings -}}
+- ` + "`{{ .Name }}`" + ` in ` + "`{{ .Locale }}`" + ` locale
+{{ end }}
+{{ end -}}
+{{ .footer }}`)
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to parse artifact template"))
+	}
+
+	var content bytes.Buffer
+	err = artifactTemplate.Execute(&content, map[string]interface{}{
+		"title":  title,
+		"footer": markdownFooter(),
+		"report": report,
+	})
+
+	if err != nil {
+		panic(errors.Wrap(err, "unable to render data as artifact report"))
+	}
+
+	return content.String()
+}
+
+// renderMarkdownTable pretty prints the slice of StringResource as Markdown
+// table to be used with Markdown format.
+func renderMarkdownTable(data []translations.StringResource) string {
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, tableColumns[column].Header)
+	}
+
+	rows := make([][]string, 0, len(data))
+	for i, item := range data {
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, tableColumns[column].Value(i, item))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return renderTable(header, rows)
+}
+
+// localeStatusColumn is the column of the per-locale tables that replaces the
+// missing, outdated and identical locales columns.
+var localeStatusColumn = tableColumn{"Status", func(i int, res translations.StringResource) string {
+	if len(res.MissingLocales) > 0 {
+		return "Missing"
+	}
+
+	return "Potentially Outdated"
+}}
+
+// renderLocaleSections pretty prints a Markdown section for each locale of the given
+// report that has missing or outdated strings, with a table of those strings. The
+// '--max-rows' limit applies to each table.
+func renderLocaleSections(report translations.Report) string {
+	sectionColumns := make([]tableColumn, 0, len(columns)+1)
+	for _, column := range columns {
+		switch column {
+		case "missing", "outdated", "identical":
+			continue
+		}
+
+		sectionColumns = append(sectionColumns, tableColumns[column])
+	}
+
+	sectionColumns = append(sectionColumns, localeStatusColumn)
+	header := make([]string, 0, len(sectionColumns))
+	for _, column := range sectionColumns {
+		header = append(header, column.Header)
+	}
+
+	sections := make([]string, 0, len(report.Locales))
+	for _, locale := range report.Locales {
+		strs := report.FilterByLocale(locale).Strings
+		if len(strs) == 0 {
+			continue
+		}
+
+		rows := make([][]string, 0, len(strs))
+		for i, item := range strs {
+			if maxRows > 0 && i == maxRows {
+				break
+			}
+
+			row := make([]string, 0, len(sectionColumns))
+			for _, column := range sectionColumns {
+				row = append(row, column.Value(i, item))
+			}
+
+			rows = append(rows, row)
+		}
+
+		section := fmt.Sprintf("## %s\n\n%s", joinLocales([]string{locale}), renderTable(header, rows))
+		if len(strs) > len(rows) {
+			section += fmt.Sprintf("\n_...and %d more._\n", len(strs)-len(rows))
+		}
+
+		sections = append(sections, section)
+	}
+
+	return strings.Join(sections, "\n")
+}
+
+// renderDivergencesTable pretty prints the given divergences as Markdown table. It
+// returns an empty string if there are no divergences.
+func renderDivergencesTable(divergences []translations.Divergence) string {
+	if len(divergences) == 0 {
+		return ""
+	}
+
+	rows := make([][]string, 0, len(divergences))
+	for _, d := range divergences {
+		rows = append(rows, []string{fmt.Sprintf("`%s`", d.Name), d.Locale, d.Value, d.ReferenceValue})
+	}
+
+	return renderTable([]string{"Name", "Locale", "Value", "Reference Value"}, rows)
+}
+
+// renderTable pretty prints the given header and rows as Markdown table.
+func renderTable(header []string, rows [][]string) string {
+	var tableContent bytes.Buffer
+	table := tablewriter.NewWriter(&tableContent)
+	table.SetBorders(tablewriter.Border{Left: true, Right: true})
+	table.SetCenterSeparator("|")
+	// wrapped cells break the rows of Markdown tables, which can't span lines
+	table.SetAutoWrapText(false)
+	table.SetReflowDuringAutoWrap(false)
+	table.SetHeader(header)
+	table.AppendBulk(rows)
+	table.Render()
+	return tableContent.String()
+}
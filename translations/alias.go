@@ -0,0 +1,190 @@
+package translations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BuiltinLocaleAliases maps the legacy language codes that Android still uses for
+// the resource directories to their modern ISO 639 codes.
+var BuiltinLocaleAliases = map[string]string{
+	"iw": "he",  // Hebrew
+	"in": "id",  // Indonesian
+	"ji": "yi",  // Yiddish
+	"tl": "fil", // Filipino
+}
+
+// AliasLocale returns the alias of the given locale in 'aliases'. If the locale
+// itself has no alias, its language is replaced by the alias of the language, e.g.
+// 'he-rIL' for 'iw-rIL' with the 'iw' => 'he' alias. Locales without any alias are
+// returned as is.
+func AliasLocale(locale string, aliases map[string]string) string {
+	if alias, ok := aliases[locale]; ok {
+		return alias
+	}
+
+	split := strings.SplitN(locale, "-", 2)
+	if alias, ok := aliases[split[0]]; ok {
+		split[0] = alias
+		return strings.Join(split, "-")
+	}
+
+	return locale
+}
+
+// WithLocaleAliases returns a copy of the report in which the locales are replaced
+// by their aliases as per AliasLocale, e.g. to match the locale codes of a
+// translation platform. It only changes how the locales are reported, so it should
+// be applied after comparing the report to a baseline. If more than one locale has
+// the same alias, their findings are merged, only the coverage of the first one is
+// kept and a warning is added to the report.
+func (r Report) WithLocaleAliases(aliases map[string]string) Report {
+	if len(aliases) == 0 {
+		return r
+	}
+
+	alias := func(locale string) string { return AliasLocale(locale, aliases) }
+	aliased := r
+	aliased.Locales = make([]string, 0, len(r.Locales))
+	aliased.LocaleCoverage = map[string]float64{}
+	aliased.TypeCounts = map[string]map[string]TypeCount{}
+	aliased.Warnings = append([]string{}, r.Warnings...)
+	seen := map[string]string{}
+	for _, locale := range r.Locales {
+		a := alias(locale)
+		if other, ok := seen[a]; ok {
+			aliased.Warnings = append(aliased.Warnings, fmt.Sprintf("locales %q and %q have the same alias %q", other, locale, a))
+			continue
+		}
+
+		seen[a] = locale
+		aliased.Locales = append(aliased.Locales, a)
+		aliased.LocaleCoverage[a] = r.LocaleCoverage[locale]
+		aliased.TypeCounts[a] = r.TypeCounts[locale]
+	}
+
+	sort.Strings(aliased.Locales)
+	aliased.Strings = make([]StringResource, 0, len(r.Strings))
+	for _, res := range r.Strings {
+		res = res.WithLocaleAliases(aliases)
+		if res.MissingLocalesDisplay != nil || res.OutdatedLocalesDisplay != nil {
+			res.MissingLocalesDisplay = localeDisplayNames(res.MissingLocales, r.DefaultLanguage)
+			res.OutdatedLocalesDisplay = localeDisplayNames(res.OutdatedLocales, r.DefaultLanguage)
+		}
+
+		aliased.Strings = append(aliased.Strings, res)
+	}
+
+	aliased.Duplicates = make([]DuplicateString, len(r.Duplicates))
+	for i, dup := range r.Duplicates {
+		dup.Locale = alias(dup.Locale)
+		aliased.Duplicates[i] = dup
+	}
+
+	aliased.OutdatedDiffs = make([]OutdatedDiff, len(r.OutdatedDiffs))
+	for i, diff := range r.OutdatedDiffs {
+		diff.Locale = alias(diff.Locale)
+		aliased.OutdatedDiffs[i] = diff
+	}
+
+	aliased.PunctuationWarnings = make([]PunctuationWarning, len(r.PunctuationWarnings))
+	for i, w := range r.PunctuationWarnings {
+		w.Locale = alias(w.Locale)
+		aliased.PunctuationWarnings[i] = w
+	}
+
+	aliased.LengthWarnings = make([]LengthWarning, len(r.LengthWarnings))
+	for i, w := range r.LengthWarnings {
+		w.Locale = alias(w.Locale)
+		aliased.LengthWarnings[i] = w
+	}
+
+	aliased.EscapeWarnings = make([]EscapeWarning, len(r.EscapeWarnings))
+	for i, w := range r.EscapeWarnings {
+		w.Locale = alias(w.Locale)
+		aliased.EscapeWarnings[i] = w
+	}
+
+	aliased.XliffMismatches = make([]XliffMismatch, len(r.XliffMismatches))
+	for i, m := range r.XliffMismatches {
+		m.Locale = alias(m.Locale)
+		aliased.XliffMismatches[i] = m
+	}
+
+	aliased.Divergences = make([]Divergence, len(r.Divergences))
+	for i, divergence := range r.Divergences {
+		divergence.Locale = alias(divergence.Locale)
+		aliased.Divergences[i] = divergence
+	}
+
+	if r.UnexpectedLocales != nil {
+		aliased.UnexpectedLocales = aliasLocales(r.UnexpectedLocales, alias)
+	}
+
+	if r.EmptyLocales != nil {
+		aliased.EmptyLocales = aliasLocales(r.EmptyLocales, alias)
+	}
+
+	aliased.FixedGaps = make([]Gap, len(r.FixedGaps))
+	for i, gap := range r.FixedGaps {
+		gap.Locale = alias(gap.Locale)
+		aliased.FixedGaps[i] = gap
+	}
+
+	return aliased
+}
+
+// WithLocaleAliases returns a copy of the string in which the locales are replaced
+// by their aliases as per AliasLocale.
+func (res StringResource) WithLocaleAliases(aliases map[string]string) StringResource {
+	if len(aliases) == 0 {
+		return res
+	}
+
+	alias := func(locale string) string { return AliasLocale(locale, aliases) }
+	res.MissingLocales = aliasLocales(res.MissingLocales, alias)
+	res.OutdatedLocales = aliasLocales(res.OutdatedLocales, alias)
+	res.IdenticalLocales = aliasLocales(res.IdenticalLocales, alias)
+	if res.MissingLocalesDisplay != nil || res.OutdatedLocalesDisplay != nil {
+		res.MissingLocalesDisplay = LocaleDisplayNames(res.MissingLocales)
+		res.OutdatedLocalesDisplay = LocaleDisplayNames(res.OutdatedLocales)
+	}
+
+	if res.OutdatedLocalesModifiedBy != nil {
+		modifiedBy := map[string]string{}
+		for locale, committer := range res.OutdatedLocalesModifiedBy {
+			modifiedBy[alias(locale)] = committer
+		}
+
+		res.OutdatedLocalesModifiedBy = modifiedBy
+	}
+
+	if res.OutdatedLocalesLag != nil {
+		lag := map[string]int64{}
+		for locale, seconds := range res.OutdatedLocalesLag {
+			if current, ok := lag[alias(locale)]; !ok || seconds > current {
+				lag[alias(locale)] = seconds
+			}
+		}
+
+		res.OutdatedLocalesLag = lag
+	}
+
+	return res
+}
+
+// aliasLocales returns the sorted aliases of the given locales without duplicates.
+func aliasLocales(locales []string, alias func(string) string) []string {
+	aliased := make([]string, 0, len(locales))
+	seen := map[string]bool{}
+	for _, locale := range locales {
+		if a := alias(locale); !seen[a] {
+			seen[a] = true
+			aliased = append(aliased, a)
+		}
+	}
+
+	sort.Strings(aliased)
+	return aliased
+}
@@ -0,0 +1,197 @@
+package translations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/language"
+)
+
+// Input formats of the scanned projects.
+const (
+	AndroidInputFormat = "android" // values XML files of Android projects
+	ARBInputFormat     = "arb"     // Application Resource Bundle files of Flutter projects, e.g. 'intl_fr.arb'
+)
+
+// DefaultARBLocale is the source locale of ARB projects if Options.DefaultLocale is
+// empty, i.e. the locale of the template ARB file that Flutter uses by default.
+const DefaultARBLocale = "en"
+
+// arbMetadata declares data structure for unmarshalling the '@key' metadata of
+// the messages in ARB files.
+type arbMetadata struct {
+	Description string `json:"description"`
+}
+
+// isARBFile checks if the given path has an '.arb' extension and if its name
+// doesn't match any of the 'ignoreFiles' glob patterns.
+func isARBFile(path string, ignoreFiles []string) bool {
+	for _, pattern := range ignoreFiles {
+		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+			return false
+		}
+	}
+
+	return strings.EqualFold(".arb", filepath.Ext(path))
+}
+
+// findARBStrings returns the messages of the given ARB files as translatable
+// strings, keyed by the '@@locale' of the files, or the locale suffix of their
+// names, e.g. 'fr' for 'intl_fr.arb', if they don't declare it. The '@key'
+// descriptions are the comments of the strings. Like for the values files, a
+// message that is defined in more than one file of a locale is a duplicate and
+// its first definition is used.
+func (s *scanner) findARBStrings(files []string) (localeStringsMap, []DuplicateString, error) {
+	strResources := make(localeStringsMap, 0)
+	duplicates := make([]DuplicateString, 0)
+	firstFiles := map[string]map[string]string{} // file of the first definition of each message per locale
+	s.valuesLocales = map[string]bool{}
+	for i, file := range files {
+		if s.opts.OnProgress != nil {
+			s.opts.OnProgress(i, len(files))
+		}
+
+		locale, strs, err := parseARBFile(file)
+		if err != nil {
+			if !s.opts.SkipInvalid {
+				return nil, nil, err
+			}
+
+			s.warnf("skipping invalid ARB file: %s", err)
+			continue
+		}
+
+		if locale == "" {
+			s.warnf("skipping ARB file %s without @@locale or a locale suffix in its name", s.relPath(file))
+			continue
+		}
+
+		s.valuesLocales[locale] = true
+		if _, ok := strResources[locale]; !ok && len(strs) > 0 {
+			strResources[locale] = map[string]xmlStringResource{}
+			firstFiles[locale] = map[string]string{}
+		}
+
+		for _, str := range strs {
+			if firstFile, ok := firstFiles[locale][str.Name]; ok {
+				duplicates = append(duplicates, DuplicateString{
+					Name:      str.Name,
+					Locale:    locale,
+					File:      s.relPath(file),
+					FirstFile: s.relPath(firstFile),
+				})
+
+				continue
+			}
+
+			firstFiles[locale][str.Name] = file
+			str.File = file
+			if locale == getSourceLocale(s.opts) && s.isBlank(str) {
+				strResources[locale][str.Name] = str
+				continue
+			}
+
+			if s.canBlame(file) {
+				// the value of a message is on the line of its key
+				str.LastModified, str.LastModifiedBy, str.LastCommit, err = getLastModified(file, str.Line, 1)
+				if err != nil {
+					s.warn(err)
+					str.LastModified = s.now()
+				}
+			}
+
+			strResources[locale][str.Name] = str
+		}
+	}
+
+	if s.opts.OnProgress != nil {
+		s.opts.OnProgress(len(files), len(files))
+	}
+
+	return strResources, duplicates, nil
+}
+
+// parseARBFile reads and parses the given ARB file. It returns the locale of the
+// file and its messages in the order of the file, with the lines of their keys and
+// their '@key' descriptions as comments.
+func parseARBFile(file string) (string, []xmlStringResource, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "unable to read file at %s", file)
+	}
+
+	// the keys are read one by one for their lines, which git blame needs
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return "", nil, fmt.Errorf("unable to parse ARB file at %s: not a JSON object", file)
+	}
+
+	locale := arbFileLocale(file)
+	strs := make([]xmlStringResource, 0)
+	descriptions := map[string]string{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "unable to parse ARB file at %s", file)
+		}
+
+		key, _ := token.(string)
+		line := bytes.Count(content[:decoder.InputOffset()], []byte("\n")) + 1
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return "", nil, errors.Wrapf(err, "unable to parse ARB file at %s", file)
+		}
+
+		switch {
+		case key == "@@locale":
+			if err := json.Unmarshal(value, &locale); err != nil {
+				return "", nil, errors.Wrapf(err, "invalid @@locale in ARB file at %s", file)
+			}
+		case strings.HasPrefix(key, "@"):
+			// '@key' metadata, or global attributes like '@@last_modified'
+			var metadata arbMetadata
+			if json.Unmarshal(value, &metadata) == nil {
+				descriptions[strings.TrimPrefix(key, "@")] = metadata.Description
+			}
+		default:
+			str := xmlStringResource{Name: key, Type: StringType, Line: line}
+			if err := json.Unmarshal(value, &str.Value); err != nil {
+				return "", nil, fmt.Errorf("unable to parse ARB file at %s: value of %q is not a string", file, key)
+			}
+
+			str.InnerXML = str.Value
+			strs = append(strs, str)
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil && err != io.EOF {
+		return "", nil, errors.Wrapf(err, "unable to parse ARB file at %s", file)
+	}
+
+	for i := range strs {
+		strs[i].Comment = descriptions[strs[i].Name]
+	}
+
+	return locale, strs, nil
+}
+
+// arbFileLocale returns the locale suffix of the name of the given ARB file, e.g.
+// 'pt_BR' for 'intl_pt_BR.arb', or an empty string if it has none.
+func arbFileLocale(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		suffix := strings.Join(parts[i:], "_")
+		if _, err := language.Parse(strings.ReplaceAll(suffix, "_", "-")); err == nil {
+			return suffix
+		}
+	}
+
+	return ""
+}
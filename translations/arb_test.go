@@ -0,0 +1,146 @@
+package translations
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestArbFileLocale(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"lib/l10n/intl_fr.arb", "fr"},
+		{"lib/l10n/intl_pt_BR.arb", "pt_BR"},
+		{"lib/l10n/app_localizations_zh_Hant_TW.arb", "zh_Hant_TW"},
+		{"lib/l10n/app_en.arb", "en"},
+		{"lib/l10n/intl.arb", ""},
+		{"lib/l10n/messages_unknownlocale.arb", ""},
+	}
+
+	for _, test := range tests {
+		if got := arbFileLocale(test.file); got != test.want {
+			t.Errorf("arbFileLocale(%q) = %q, want %q", test.file, got, test.want)
+		}
+	}
+}
+
+func TestParseARBFile(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{
+		"intl_de.arb": `{
+  "@@locale": "fr",
+  "@@last_modified": "2021-01-01T12:00:00Z",
+  "hello": "Bonjour",
+  "@hello": {
+    "description": "Greeting on the home screen"
+  },
+  "messages": "{count, plural, =0{Aucun message} one{Un message} other{{count} messages}}",
+  "@messages": {
+    "description": "Number of new messages",
+    "placeholders": {"count": {"type": "int"}}
+  }
+}`,
+		"intl_es.arb": `{"hello": "Hola", "@hello": {}}`,
+	})
+
+	locale, strs, err := parseARBFile(filepath.Join(dir, "intl_de.arb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// '@@locale' takes precedence over the locale in the file name
+	if locale != "fr" {
+		t.Errorf("parseARBFile() locale = %q, want %q", locale, "fr")
+	}
+
+	// the ICU plural message is a single string, and the metadata aren't strings
+	want := []xmlStringResource{
+		{Name: "hello", Type: StringType, Value: "Bonjour", InnerXML: "Bonjour", Line: 4, Comment: "Greeting on the home screen"},
+		{
+			Name:     "messages",
+			Type:     StringType,
+			Value:    "{count, plural, =0{Aucun message} one{Un message} other{{count} messages}}",
+			InnerXML: "{count, plural, =0{Aucun message} one{Un message} other{{count} messages}}",
+			Line:     8,
+			Comment:  "Number of new messages",
+		},
+	}
+
+	if !reflect.DeepEqual(strs, want) {
+		t.Errorf("parseARBFile() strings = %+v, want %+v", strs, want)
+	}
+
+	locale, strs, err = parseARBFile(filepath.Join(dir, "intl_es.arb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if locale != "es" {
+		t.Errorf("parseARBFile() locale = %q, want %q from the file name", locale, "es")
+	}
+
+	want = []xmlStringResource{{Name: "hello", Type: StringType, Value: "Hola", InnerXML: "Hola", Line: 1}}
+	if !reflect.DeepEqual(strs, want) {
+		t.Errorf("parseARBFile() strings = %+v, want %+v", strs, want)
+	}
+}
+
+func TestParseARBFileInvalid(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{
+		"intl_fr.arb": `["hello"]`,
+		"intl_de.arb": `{"count": 1}`,
+		"intl_es.arb": `{"hello": "Hola"`,
+		"intl_it.arb": `{"@@locale": 1}`,
+	})
+
+	for _, name := range []string{"intl_fr.arb", "intl_de.arb", "intl_es.arb", "intl_it.arb"} {
+		if _, _, err := parseARBFile(filepath.Join(dir, name)); err == nil {
+			t.Errorf("parseARBFile(%q) error = nil, want an error", name)
+		}
+	}
+}
+
+func TestScanARB(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{
+		"lib/l10n/intl_en.arb": `{
+  "@@locale": "en",
+  "hello": "Hello",
+  "@hello": {"description": "Greeting"},
+  "bye": "Bye",
+  "messages": "{count, plural, one{One message} other{{count} messages}}"
+}`,
+		// the '@@locale' of 'intl_fr_CA.arb' makes it a second file of 'fr'
+		"lib/l10n/intl_fr.arb":    `{"hello": "Bonjour", "bye": "Au revoir"}`,
+		"lib/l10n/intl_fr_CA.arb": `{"@@locale": "fr", "hello": "Allô", "messages": "{count, plural, one{Un message} other{{count} messages}}"}`,
+		"lib/l10n/intl_de.arb":    `{"hello": "Hallo", "hello": "Guten Tag"}`,
+	})
+
+	report, err := Scan(dir, Options{InputFormat: ARBInputFormat, SkipOutdated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"de", "fr"}; !reflect.DeepEqual(report.Locales, want) {
+		t.Errorf("Scan() locales = %v, want %v", report.Locales, want)
+	}
+
+	missing := map[string][]string{}
+	for _, str := range report.Strings {
+		missing[str.Name] = str.MissingLocales
+	}
+
+	want := map[string][]string{"bye": {"de"}, "messages": {"de"}}
+	if !reflect.DeepEqual(missing, want) {
+		t.Errorf("Scan() missing locales = %v, want %v", missing, want)
+	}
+
+	duplicates := []DuplicateString{
+		{Name: "hello", Locale: "de", File: filepath.FromSlash("lib/l10n/intl_de.arb"), FirstFile: filepath.FromSlash("lib/l10n/intl_de.arb")},
+		{Name: "hello", Locale: "fr", File: filepath.FromSlash("lib/l10n/intl_fr_CA.arb"), FirstFile: filepath.FromSlash("lib/l10n/intl_fr.arb")},
+	}
+
+	if !reflect.DeepEqual(report.Duplicates, duplicates) {
+		t.Errorf("Scan() duplicates = %+v, want %+v", report.Duplicates, duplicates)
+	}
+}
@@ -0,0 +1,164 @@
+package translations
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DroppedString declares the output structure for a string that is translated in
+// the values files of a locale but isn't in that locale of a built artifact.
+type DroppedString struct {
+	Name   string `json:"name"` // name of the resource, i.e. of the string array or plurals for their items
+	Locale string `json:"locale"`
+}
+
+// ArtifactReport declares the output structure for the comparison of the values
+// files of an Android project with the resources of a built APK.
+type ArtifactReport struct {
+	Artifact       string          `json:"artifact"`
+	Locales        []string        `json:"locales"`         // sorted locales that have strings in the artifact
+	DroppedLocales []string        `json:"dropped_locales"` // sorted locales that have strings in the values files but not in the artifact
+	DroppedStrings []DroppedString `json:"dropped_strings"` // sorted by the names and locales, excluding the ones of DroppedLocales
+	Warnings       []string        `json:"-"`
+}
+
+// artifactResourceExpr matches the resource lines of 'aapt2 dump resources', e.g.
+// 'resource 0x7f0b0000 string/app_name'. The groups are the type and the name.
+var artifactResourceExpr = regexp.MustCompile(`^\s*resource\s+0x[0-9a-fA-F]+\s+(\w+)/(\S+)`)
+
+// artifactValueExpr matches the value lines of 'aapt2 dump resources', e.g.
+// '(de-rAT) "Hallo"' and '() (array) size=2'. The group is the configuration.
+var artifactValueExpr = regexp.MustCompile(`^\s*\(([^)]*)\)`)
+
+// CompareArtifact compares the translatable strings in the values files of the
+// Android project at 'dir' with the string resources of the APK at 'artifact', e.g.
+// to find the strings that resource shrinking or 'resConfigs' dropped from a
+// release build. The resources of the APK are read with 'aapt2 dump resources', so
+// 'aapt2' of the Android SDK build tools must be on the PATH. String arrays and
+// plurals are compared as a whole.
+func CompareArtifact(dir, artifact string, opts Options) (ArtifactReport, error) {
+	opts.SkipOutdated = true // git history is irrelevant for the artifact
+	s := &scanner{dir: dir, opts: opts}
+	localeStrings, _, _, err := s.findLocaleStrings()
+	if err != nil {
+		return ArtifactReport{}, err
+	}
+
+	artifactStrings, err := readArtifactStrings(artifact)
+	if err != nil {
+		return ArtifactReport{}, err
+	}
+
+	report := ArtifactReport{
+		Artifact:       artifact,
+		Locales:        make([]string, 0, len(artifactStrings)),
+		DroppedLocales: make([]string, 0),
+		DroppedStrings: make([]DroppedString, 0),
+	}
+
+	for locale := range artifactStrings {
+		report.Locales = append(report.Locales, locale)
+	}
+
+	sort.Strings(report.Locales)
+	for _, locale := range localeStrings.sortedLocales() {
+		names, ok := artifactStrings[locale]
+		if !ok {
+			report.DroppedLocales = append(report.DroppedLocales, locale)
+			continue
+		}
+
+		seen := map[string]bool{}
+		for _, str := range localeStrings[locale] {
+			name := str.Name
+			if str.Parent != "" {
+				name = str.Parent
+			}
+
+			if !names[name] && !seen[name] {
+				seen[name] = true
+				report.DroppedStrings = append(report.DroppedStrings, DroppedString{Name: name, Locale: locale})
+			}
+		}
+	}
+
+	sort.SliceStable(report.DroppedStrings, func(i, j int) bool {
+		if report.DroppedStrings[i].Name != report.DroppedStrings[j].Name {
+			return report.DroppedStrings[i].Name < report.DroppedStrings[j].Name
+		}
+
+		return report.DroppedStrings[i].Locale < report.DroppedStrings[j].Locale
+	})
+
+	report.Warnings = s.warnings
+	return report, nil
+}
+
+// readArtifactStrings returns the names of the '<string>', '<string-array>' and
+// '<plurals>' resources in the APK at the given path mapped by their locales. The
+// values of configurations with qualifiers other than MCC, MNC and locale, e.g.
+// 'night' and 'de-v21', are left out like their values files.
+func readArtifactStrings(artifact string) (map[string]map[string]bool, error) {
+	cmd := exec.Command("aapt2", "dump", "resources", artifact)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Wrapf(err, "unable to dump resources of %s: %s", artifact, msg)
+		}
+
+		return nil, errors.Wrapf(err, "unable to dump resources of %s", artifact)
+	}
+
+	artifactStrings := map[string]map[string]bool{}
+	var name string // name of the string resource whose values are being read
+	lines := bufio.NewScanner(bytes.NewReader(output))
+	lines.Buffer(make([]byte, 0, 64*1024), 1024*1024) // values may be long
+	for lines.Scan() {
+		if match := artifactResourceExpr.FindStringSubmatch(lines.Text()); match != nil {
+			name = ""
+			switch match[1] {
+			case "string", "array", "plurals":
+				name = match[2]
+			}
+
+			continue
+		}
+
+		match := artifactValueExpr.FindStringSubmatch(lines.Text())
+		if name == "" || match == nil {
+			continue
+		}
+
+		// configurations have the form of values directory suffixes, e.g. 'de-rAT'
+		path := filepath.Join("values", "strings.xml")
+		if match[1] != "" {
+			path = filepath.Join("values-"+match[1], "strings.xml")
+		}
+
+		if hasConfigQualifiers(path) {
+			continue
+		}
+
+		locale := getLocaleForValuesFile(path)
+		if artifactStrings[locale] == nil {
+			artifactStrings[locale] = map[string]bool{}
+		}
+
+		artifactStrings[locale][name] = true
+	}
+
+	if err := lines.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read resources of %s", artifact)
+	}
+
+	return artifactStrings, nil
+}
@@ -0,0 +1,152 @@
+package translations
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// writeZip writes a ZIP archive with the given contents of entries, keyed by their
+// paths, to the given path.
+func writeZip(t *testing.T, path string, entries map[string]string) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+	w := zip.NewWriter(file)
+	for name, content := range entries {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// artifactDump is the output of 'aapt2 dump resources' for the test APK.
+const artifactDump = `Binary APK
+Package name=com.example id=7f
+  type array id=01 entryCount=1
+    resource 0x7f010000 array/planets
+      () (array) size=2
+      (de) (array) size=2
+  type string id=02 entryCount=4
+    resource 0x7f020000 string/hello
+      () "Hello"
+      (de) "Hallo"
+    resource 0x7f020001 string/bye
+      () "Bye"
+      (de-night) "Gute Nacht"
+    resource 0x7f020002 string/lib_title
+      () "Library"
+    resource 0x7f020003 string/settings
+      () "Settings"
+      (mcc310-de) "Einstellungen"
+  type dimen id=03 entryCount=1
+    resource 0x7f030000 dimen/margin
+      (de) 16.000000dp
+`
+
+func TestCompareArtifact(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake aapt2 is a shell script")
+	}
+
+	dir := writeValuesFiles(t, map[string]string{
+		"res/values/strings.xml": `<resources>
+  <string name="hello">Hello</string>
+  <string name="bye">Bye</string>
+  <string name="settings">Settings</string>
+  <string-array name="planets"><item>Mercury</item><item>Venus</item></string-array>
+</resources>`,
+		"res/values-de/strings.xml": `<resources>
+  <string name="hello">Hallo</string>
+  <string name="bye">Tschüss</string>
+  <string name="settings">Einstellungen</string>
+  <string-array name="planets"><item>Merkur</item><item>Venus</item></string-array>
+</resources>`,
+	})
+
+	// the strings of the library are in the artifact, but not its French translations
+	if err := os.MkdirAll(filepath.Join(dir, "libs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeZip(t, filepath.Join(dir, "libs", "lib.aar"), map[string]string{
+		"res/values/strings.xml":    `<resources><string name="lib_title">Library</string></resources>`,
+		"res/values-fr/strings.xml": `<resources><string name="lib_title">Bibliothèque</string></resources>`,
+	})
+
+	apk := filepath.Join(dir, "app-release.apk")
+	writeZip(t, apk, map[string]string{"resources.arsc": ""})
+	bin := writeValuesFiles(t, map[string]string{"dump.txt": artifactDump})
+	script := `#!/bin/sh
+[ "$1 $2 $3" = "dump resources ` + apk + `" ] || { echo "unexpected arguments: $*" >&2; exit 1; }
+cat "` + filepath.Join(bin, "dump.txt") + `"
+`
+
+	if err := ioutil.WriteFile(filepath.Join(bin, "aapt2"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	report, err := CompareArtifact(dir, apk, Options{ScanArchives: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ArtifactReport{
+		Artifact:       apk,
+		Locales:        []string{"de", DefaultLocale},
+		DroppedLocales: []string{"fr"}, // of the values files in 'libs/lib.aar!/res'
+		// 'bye' is only in a night configuration, 'settings' in an MCC configuration
+		DroppedStrings: []DroppedString{{Name: "bye", Locale: "de"}},
+	}
+
+	report.Warnings = nil
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("CompareArtifact() = %+v, want %+v", report, want)
+	}
+
+	// the strings of the library are found at the paths of their archive entries
+	scan, err := Scan(dir, Options{ScanArchives: true, SkipOutdated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for _, str := range scan.Strings {
+		files[str.Name] = str.File
+	}
+
+	if file, want := files["lib_title"], filepath.Join("libs", "lib.aar")+"!/res/values/strings.xml"; file != want || !isArchiveEntry(file) {
+		t.Errorf("Scan() file of lib_title = %q, want the archive entry %q", file, want)
+	}
+}
+
+func TestCompareArtifactWithoutAapt2(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{
+		"res/values/strings.xml": `<resources><string name="hello">Hello</string></resources>`,
+	})
+
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir)
+	if _, err := CompareArtifact(dir, filepath.Join(dir, "app.apk"), Options{}); err == nil {
+		t.Error("CompareArtifact() without aapt2 error = nil, want an error")
+	}
+}
@@ -0,0 +1,196 @@
+package translations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of the translation gaps.
+const (
+	MissingGap  = "missing"
+	OutdatedGap = "outdated"
+)
+
+// Gap declares a single string that is missing or potentially outdated in a locale.
+type Gap struct {
+	Name   string `json:"name"`
+	Locale string `json:"locale"`
+	Kind   string `json:"kind"` // one of MissingGap or OutdatedGap
+}
+
+// baselineFile declares the structure of the baseline file.
+type baselineFile struct {
+	Gaps []Gap `json:"gaps"`
+}
+
+// Gaps returns all the (string, locale) gaps in the report sorted by the string
+// names, locales and kinds.
+func (r Report) Gaps() []Gap {
+	return gapsOf(r.Strings)
+}
+
+// gapsOf returns all the (string, locale) gaps of the given strings sorted by the
+// string names, locales and kinds.
+func gapsOf(strs []StringResource) []Gap {
+	gaps := make([]Gap, 0)
+	for _, res := range strs {
+		for _, locale := range res.MissingLocales {
+			gaps = append(gaps, Gap{Name: res.Name, Locale: locale, Kind: MissingGap})
+		}
+
+		for _, locale := range res.OutdatedLocales {
+			gaps = append(gaps, Gap{Name: res.Name, Locale: locale, Kind: OutdatedGap})
+		}
+	}
+
+	sortGaps(gaps)
+	return gaps
+}
+
+// ApplyBaseline returns a copy of the report without the gaps that are present in
+// the given baseline, so that only the newly introduced gaps are reported. The gaps
+// that are in the baseline but no longer in the report are returned in FixedGaps
+// of the returned report and recorded as warnings.
+func (r Report) ApplyBaseline(baseline []Gap) Report {
+	inBaseline := map[Gap]bool{}
+	for _, gap := range baseline {
+		inBaseline[gap] = true
+	}
+
+	inReport := map[Gap]bool{}
+	filtered := r
+	filtered.Strings = make([]StringResource, 0)
+	for _, res := range r.Strings {
+		missing := make([]string, 0)
+		for _, locale := range res.MissingLocales {
+			gap := Gap{Name: res.Name, Locale: locale, Kind: MissingGap}
+			inReport[gap] = true
+			if !inBaseline[gap] {
+				missing = append(missing, locale)
+			}
+		}
+
+		outdated := make([]string, 0)
+		for _, locale := range res.OutdatedLocales {
+			gap := Gap{Name: res.Name, Locale: locale, Kind: OutdatedGap}
+			inReport[gap] = true
+			if !inBaseline[gap] {
+				outdated = append(outdated, locale)
+			}
+		}
+
+		if len(missing)+len(outdated) == 0 {
+			continue
+		}
+
+		res.MissingLocales, res.OutdatedLocales = missing, outdated
+		if r.opts.LocaleNames {
+			res.MissingLocalesDisplay = localeDisplayNames(res.MissingLocales, r.DefaultLanguage)
+			res.OutdatedLocalesDisplay = localeDisplayNames(res.OutdatedLocales, r.DefaultLanguage)
+		}
+
+		if r.opts.ShowAuthors {
+			res.OutdatedLocalesModifiedBy = filterModifiedBy(res.OutdatedLocalesModifiedBy, res.OutdatedLocales)
+		}
+
+		if r.opts.ShowStaleness {
+			res.OutdatedLocalesLag = filterLag(res.OutdatedLocalesLag, res.OutdatedLocales)
+		}
+
+		if len(res.OutdatedLocales) == 0 {
+			res.OutdatingCommit = nil
+		}
+
+		filtered.Strings = append(filtered.Strings, res)
+	}
+
+	filtered.FixedGaps = make([]Gap, 0)
+	filtered.Warnings = append([]string{}, r.Warnings...)
+	for _, gap := range baseline {
+		if !inReport[gap] {
+			filtered.FixedGaps = append(filtered.FixedGaps, gap)
+			const warnFmt = "string %q is no longer %s in locale %q, consider updating the baseline"
+			filtered.Warnings = append(filtered.Warnings, fmt.Sprintf(warnFmt, gap.Name, gap.Kind, gap.Locale))
+		}
+	}
+
+	filtered.setCounts(countAffectedStrings(filtered.Strings))
+	return filtered
+}
+
+// ReadBaseline reads the gaps from the baseline file at the given path.
+func ReadBaseline(path string) ([]Gap, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read baseline file at %s", path)
+	}
+
+	baseline := &baselineFile{}
+	if err := json.Unmarshal(content, baseline); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse baseline file at %s", path)
+	}
+
+	return baseline.Gaps, nil
+}
+
+// ReadReportGaps reads the gaps from a JSON report at the given path, i.e. the
+// strings as a JSON array or the JSON envelope with the strings in its 'resources'
+// field, e.g. the report of a previous run.
+func ReadReportGaps(path string) ([]Gap, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read report at %s", path)
+	}
+
+	var strs []StringResource
+	if content = bytes.TrimSpace(content); bytes.HasPrefix(content, []byte("{")) {
+		envelope := struct {
+			Resources []StringResource `json:"resources"`
+		}{}
+
+		err = json.Unmarshal(content, &envelope)
+		strs = envelope.Resources
+	} else {
+		err = json.Unmarshal(content, &strs)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse report at %s", path)
+	}
+
+	return gapsOf(strs), nil
+}
+
+// WriteBaseline writes the given gaps to the baseline file at the given path.
+func WriteBaseline(path string, gaps []Gap) error {
+	content, err := json.MarshalIndent(baselineFile{Gaps: gaps}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal baseline as JSON")
+	}
+
+	if err := ioutil.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "unable to write baseline file to %s", path)
+	}
+
+	return nil
+}
+
+// sortGaps sorts the given gaps by their string names, locales and kinds.
+func sortGaps(gaps []Gap) {
+	sort.Slice(gaps, func(i, j int) bool {
+		if gaps[i].Name != gaps[j].Name {
+			return gaps[i].Name < gaps[j].Name
+		}
+
+		if gaps[i].Locale != gaps[j].Locale {
+			return gaps[i].Locale < gaps[j].Locale
+		}
+
+		return gaps[i].Kind < gaps[j].Kind
+	})
+}
@@ -0,0 +1,164 @@
+package translations
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyBaseline(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{
+		"res/values/strings.xml":    `<resources><string name="hello">Hello</string><string name="bye">Bye</string><string name="cancel">Cancel</string></resources>`,
+		"res/values-de/strings.xml": `<resources><string name="hello">Hallo</string></resources>`,
+		"res/values-fr/strings.xml": `<resources><string name="hello">Bonjour</string></resources>`,
+	})
+
+	report, err := Scan(dir, Options{SkipOutdated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseline := []Gap{
+		{Name: "bye", Locale: "de", Kind: MissingGap},
+		{Name: "cancel", Locale: "fr", Kind: MissingGap},
+		{Name: "ok", Locale: "de", Kind: MissingGap},      // the string was removed
+		{Name: "hello", Locale: "fr", Kind: MissingGap},   // the string was translated
+		{Name: "cancel", Locale: "de", Kind: OutdatedGap}, // a different kind than the gap
+	}
+
+	filtered := report.ApplyBaseline(baseline)
+	missing := map[string][]string{}
+	for _, str := range filtered.Strings {
+		missing[str.Name] = str.MissingLocales
+	}
+
+	// the gaps that aren't in the baseline are still reported
+	if want := map[string][]string{"bye": {"fr"}, "cancel": {"de"}}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("ApplyBaseline() missing locales = %v, want %v", missing, want)
+	}
+
+	if filtered.MissingCount != 2 || filtered.AffectedCount != 2 {
+		t.Errorf("ApplyBaseline() counts = %d missing, %d affected, want 2 and 2", filtered.MissingCount, filtered.AffectedCount)
+	}
+
+	if want := baseline[2:]; !reflect.DeepEqual(filtered.FixedGaps, want) {
+		t.Errorf("ApplyBaseline() fixed gaps = %+v, want %+v", filtered.FixedGaps, want)
+	}
+
+	if len(filtered.Warnings) != len(report.Warnings)+3 {
+		t.Errorf("ApplyBaseline() warnings = %q, want a warning for each stale gap", filtered.Warnings)
+	}
+
+	// the report itself isn't changed
+	if len(report.Gaps()) != 4 {
+		t.Errorf("Gaps() of the report = %+v, want 4 gaps", report.Gaps())
+	}
+
+	if len(report.ApplyBaseline(report.Gaps()).Strings) != 0 {
+		t.Error("ApplyBaseline() with the gaps of the report has strings, want none")
+	}
+}
+
+func TestWriteBaseline(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{"invalid.json": `{"gaps": [`})
+	path := filepath.Join(dir, "baseline.json")
+	gaps := []Gap{{Name: "bye", Locale: "de", Kind: MissingGap}, {Name: "hello", Locale: "fr", Kind: OutdatedGap}}
+	if err := WriteBaseline(path, gaps); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, gaps) {
+		t.Errorf("ReadBaseline() = %+v, want %+v", got, gaps)
+	}
+
+	if _, err := ReadBaseline(filepath.Join(dir, "invalid.json")); err == nil {
+		t.Error("ReadBaseline() of an invalid file error = nil, want an error")
+	}
+
+	if _, err := ReadBaseline(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("ReadBaseline() of a missing file error = nil, want an error")
+	}
+}
+
+func TestReadReportGaps(t *testing.T) {
+	strs := []StringResource{
+		{Name: "hello", MissingLocales: []string{"fr", "de"}},
+		{Name: "bye", MissingLocales: []string{}, OutdatedLocales: []string{"de"}},
+	}
+
+	array, err := json.Marshal(strs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, err := json.MarshalIndent(map[string]interface{}{"schema_version": 1, "project": "app", "resources": strs}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := writeValuesFiles(t, map[string]string{
+		"report.json":          string(array),
+		"report-envelope.json": "\n" + string(envelope) + "\n",
+		"report-empty.json":    "[]",
+		"report-invalid.json":  `{"resources": {"de": []}}`,
+	})
+
+	want := []Gap{
+		{Name: "bye", Locale: "de", Kind: OutdatedGap},
+		{Name: "hello", Locale: "de", Kind: MissingGap},
+		{Name: "hello", Locale: "fr", Kind: MissingGap},
+	}
+
+	// the plain JSON report and the JSON envelope have the same gaps
+	for _, name := range []string{"report.json", "report-envelope.json"} {
+		gaps, err := ReadReportGaps(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(gaps, want) {
+			t.Errorf("ReadReportGaps(%q) = %+v, want %+v", name, gaps, want)
+		}
+	}
+
+	if gaps, err := ReadReportGaps(filepath.Join(dir, "report-empty.json")); err != nil || len(gaps) != 0 {
+		t.Errorf("ReadReportGaps() of an empty report = %+v, %v, want no gaps", gaps, err)
+	}
+
+	for _, name := range []string{"report-invalid.json", "missing.json"} {
+		if _, err := ReadReportGaps(filepath.Join(dir, name)); err == nil {
+			t.Errorf("ReadReportGaps(%q) error = nil, want an error", name)
+		}
+	}
+
+	// a report that is written by the tool, i.e. of Report.Gaps, is read as is
+	report, err := Scan(writeValuesFiles(t, map[string]string{
+		"res/values/strings.xml":    `<resources><string name="hello">Hello</string><string name="bye">Bye</string></resources>`,
+		"res/values-de/strings.xml": `<resources><string name="hello">Hallo</string></resources>`,
+	}), Options{SkipOutdated: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := json.Marshal(report.Strings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "scan.json")
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if gaps, err := ReadReportGaps(path); err != nil || !reflect.DeepEqual(gaps, report.Gaps()) {
+		t.Errorf("ReadReportGaps() of the scan = %+v, %v, want %+v", gaps, err, report.Gaps())
+	}
+}
@@ -0,0 +1,75 @@
+package translations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// cacheSchemaVersion is the version of the structure of the cached reports. It must
+// be incremented whenever the structure of Report changes, so that the reports
+// cached by older versions are scanned again instead of being loaded incompletely.
+const cacheSchemaVersion = 1
+
+// cacheEntry declares the structure of a cached report along with the schema
+// version and the key it was cached with.
+type cacheEntry struct {
+	SchemaVersion int    `json:"schema_version"`
+	Key           string `json:"key"`
+	Report        Report `json:"report"`
+}
+
+// ScanCached is like Scan, but it stores the report in 'cacheDir' keyed by the git
+// HEAD of 'dir' and the given key, e.g. a hash of the command-line flags, so that
+// later calls with the same HEAD and key load the report from there instead of
+// scanning again. Since the report depends on the uncommitted changes, the cache is
+// neither read nor written if the working tree of 'dir' isn't clean. The cached
+// reports of other schema versions, heads or keys are ignored and overwritten. It
+// also returns true if the report was loaded from the cache.
+func ScanCached(dir, cacheDir, key string, opts Options) (Report, bool, error) {
+	if !isWorkingTreeClean(dir) {
+		report, err := Scan(dir, opts)
+		return report, false, err
+	}
+
+	head, err := getHead(dir)
+	if err != nil {
+		return Report{}, false, err
+	}
+
+	key = head + "\n" + key
+	hash := sha256.Sum256([]byte(key))
+	cacheFile := filepath.Join(cacheDir, hex.EncodeToString(hash[:])+".json")
+	if content, err := ioutil.ReadFile(cacheFile); err == nil {
+		var entry cacheEntry
+		if err := json.Unmarshal(content, &entry); err == nil && entry.SchemaVersion == cacheSchemaVersion && entry.Key == key {
+			entry.Report.opts = opts
+			return entry.Report, true, nil
+		}
+	}
+
+	report, err := Scan(dir, opts)
+	if err != nil {
+		return Report{}, false, err
+	}
+
+	content, err := json.Marshal(cacheEntry{SchemaVersion: cacheSchemaVersion, Key: key, Report: report})
+	if err != nil {
+		return Report{}, false, errors.Wrap(err, "unable to marshal report for cache")
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return Report{}, false, errors.Wrapf(err, "unable to create cache directory %s", cacheDir)
+	}
+
+	if err := ioutil.WriteFile(cacheFile, content, 0644); err != nil {
+		return Report{}, false, errors.Wrapf(err, "unable to write cache file %s", cacheFile)
+	}
+
+	return report, false, nil
+}
@@ -0,0 +1,320 @@
+package translations
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LocaleDelta declares the output structure for the coverage change of a locale
+// between two reports.
+type LocaleDelta struct {
+	Locale      string  `json:"locale"`
+	OldCoverage float64 `json:"old_coverage"` // 0 if the locale isn't in the old report
+	NewCoverage float64 `json:"new_coverage"` // 0 if the locale isn't in the new report
+	Change      float64 `json:"change"`
+}
+
+// GapDelta declares the output structure for the changes of the gaps since a
+// previous report.
+type GapDelta struct {
+	NewlyMissing  []Gap `json:"newly_missing"`
+	NewlyOutdated []Gap `json:"newly_outdated"`
+	Resolved      []Gap `json:"resolved"` // gaps of the previous report that are no longer in the report
+}
+
+// Delta declares the output structure for the changes of the translations between
+// two reports, e.g. of two releases.
+type Delta struct {
+	OldRef          string        `json:"old_ref"`
+	NewRef          string        `json:"new_ref"`
+	Locales         []LocaleDelta `json:"locales"`                 // sorted by the locales
+	NewlyTranslated []Gap         `json:"newly_translated"`        // missing in the old report but not in the new one
+	NewlyMissing    []Gap         `json:"newly_missing"`           // missing in the new report but not in the old one
+	StillMissing    []Gap         `json:"still_missing,omitempty"` // only populated by CompareTranslatedBranch
+	Warnings        []string      `json:"-"`
+}
+
+// CompareRefs scans the Android project at 'dir' as of the git refs 'oldRef' and
+// 'newRef', e.g. two release tags, and returns the changes of its translations. Each
+// ref is checked out into a temporary git worktree, so the working tree of 'dir'
+// isn't touched. The warnings of both scans are prefixed with their refs.
+func CompareRefs(dir, oldRef, newRef string, opts Options) (Delta, error) {
+	oldReport, err := scanRef(dir, oldRef, opts)
+	if err != nil {
+		return Delta{}, err
+	}
+
+	newReport, err := scanRef(dir, newRef, opts)
+	if err != nil {
+		return Delta{}, err
+	}
+
+	delta := CompareReports(oldReport, newReport)
+	delta.OldRef, delta.NewRef = oldRef, newRef
+	for _, warning := range oldReport.Warnings {
+		delta.Warnings = append(delta.Warnings, fmt.Sprintf("%s: %s", oldRef, warning))
+	}
+
+	for _, warning := range newReport.Warnings {
+		delta.Warnings = append(delta.Warnings, fmt.Sprintf("%s: %s", newRef, warning))
+	}
+
+	return delta, nil
+}
+
+// CompareTranslatedBranch scans the Android project at 'dir' with the translations
+// of the git ref 'branch', e.g. the branch that a translation vendor pushes to, and
+// returns the changes from the report of 'dir' itself. The branch is checked out
+// into a temporary git worktree and the default values files of 'dir' replace the
+// ones of the branch, so that only its translations are compared. NewlyTranslated
+// are the gaps that the branch fills and StillMissing are the gaps that it leaves.
+// Since the copied default strings have no history, outdated translations aren't
+// compared.
+func CompareTranslatedBranch(dir, branch string, opts Options) (Delta, error) {
+	opts.SkipOutdated = true
+	current, err := Scan(dir, opts)
+	if err != nil {
+		return Delta{}, err
+	}
+
+	merged, err := withRef(dir, branch, func(refDir string) (Report, error) {
+		if err := replaceDefaultValuesFiles(dir, refDir, opts); err != nil {
+			return Report{}, err
+		}
+
+		report, err := Scan(refDir, opts)
+		if err != nil {
+			return Report{}, errors.Wrapf(err, "unable to scan %s", branch)
+		}
+
+		return report, nil
+	})
+
+	if err != nil {
+		return Delta{}, err
+	}
+
+	delta := CompareReports(current, merged)
+	delta.OldRef, delta.NewRef = "HEAD", branch
+	delta.StillMissing = make([]Gap, 0)
+	for gap := range missingGaps(merged) {
+		delta.StillMissing = append(delta.StillMissing, gap)
+	}
+
+	sortGaps(delta.StillMissing)
+	delta.Warnings = append(delta.Warnings, current.Warnings...)
+	for _, warning := range merged.Warnings {
+		delta.Warnings = append(delta.Warnings, fmt.Sprintf("%s: %s", branch, warning))
+	}
+
+	return delta, nil
+}
+
+// replaceDefaultValuesFiles replaces the values files of the source locale in the
+// Android project at 'dstDir' with the ones of the Android project at 'srcDir'.
+// Values files inside archives are left as they are.
+func replaceDefaultValuesFiles(srcDir, dstDir string, opts Options) error {
+	sourceLocale := getSourceLocale(opts)
+	dstFiles, err := (&scanner{dir: dstDir, opts: opts}).findRootValuesFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range dstFiles {
+		if !isArchiveEntry(file) && getLocaleForValuesFile(file) == sourceLocale {
+			if err := os.Remove(file); err != nil {
+				return errors.Wrapf(err, "unable to remove %s", file)
+			}
+		}
+	}
+
+	srcFiles, err := (&scanner{dir: srcDir, opts: opts}).findRootValuesFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range srcFiles {
+		if isArchiveEntry(file) || getLocaleForValuesFile(file) != sourceLocale {
+			continue
+		}
+
+		rel, err := filepath.Rel(srcDir, file)
+		if err != nil {
+			return errors.Wrapf(err, "unable to copy %s", file)
+		}
+
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrapf(err, "unable to read %s", file)
+		}
+
+		dst := filepath.Join(dstDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return errors.Wrapf(err, "unable to create %s", filepath.Dir(dst))
+		}
+
+		if err := ioutil.WriteFile(dst, content, 0644); err != nil {
+			return errors.Wrapf(err, "unable to write %s", dst)
+		}
+	}
+
+	return nil
+}
+
+// CompareReports returns the changes of the translations from the 'old' report to
+// the 'new' report. The strings that were removed from the default locale are
+// reported as newly translated in the locales that were missing them.
+func CompareReports(old, new Report) Delta {
+	delta := Delta{
+		Locales:         make([]LocaleDelta, 0),
+		NewlyTranslated: make([]Gap, 0),
+		NewlyMissing:    make([]Gap, 0),
+	}
+
+	locales := map[string]bool{}
+	for locale := range old.LocaleCoverage {
+		locales[locale] = true
+	}
+
+	for locale := range new.LocaleCoverage {
+		locales[locale] = true
+	}
+
+	for locale := range locales {
+		oldCoverage, newCoverage := old.LocaleCoverage[locale], new.LocaleCoverage[locale]
+		delta.Locales = append(delta.Locales, LocaleDelta{
+			Locale:      locale,
+			OldCoverage: oldCoverage,
+			NewCoverage: newCoverage,
+			Change:      newCoverage - oldCoverage,
+		})
+	}
+
+	sort.Slice(delta.Locales, func(i, j int) bool {
+		return delta.Locales[i].Locale < delta.Locales[j].Locale
+	})
+
+	oldGaps, newGaps := missingGaps(old), missingGaps(new)
+	for gap := range oldGaps {
+		if !newGaps[gap] {
+			delta.NewlyTranslated = append(delta.NewlyTranslated, gap)
+		}
+	}
+
+	for gap := range newGaps {
+		if !oldGaps[gap] {
+			delta.NewlyMissing = append(delta.NewlyMissing, gap)
+		}
+	}
+
+	sortGaps(delta.NewlyTranslated)
+	sortGaps(delta.NewlyMissing)
+	return delta
+}
+
+// CompareGaps returns the gaps that are in 'current' but not in 'previous' and the
+// ones that are in 'previous' but not in 'current', sorted by the string names,
+// locales and kinds. A translation that was outdated and is missing now is both
+// resolved as outdated and newly missing.
+func CompareGaps(previous, current []Gap) GapDelta {
+	delta := GapDelta{
+		NewlyMissing:  make([]Gap, 0),
+		NewlyOutdated: make([]Gap, 0),
+		Resolved:      make([]Gap, 0),
+	}
+
+	inPrevious, inCurrent := map[Gap]bool{}, map[Gap]bool{}
+	for _, gap := range previous {
+		inPrevious[gap] = true
+	}
+
+	for _, gap := range current {
+		inCurrent[gap] = true
+		if inPrevious[gap] {
+			continue
+		}
+
+		if gap.Kind == MissingGap {
+			delta.NewlyMissing = append(delta.NewlyMissing, gap)
+		} else {
+			delta.NewlyOutdated = append(delta.NewlyOutdated, gap)
+		}
+	}
+
+	for gap := range inPrevious {
+		if !inCurrent[gap] {
+			delta.Resolved = append(delta.Resolved, gap)
+		}
+	}
+
+	sortGaps(delta.NewlyMissing)
+	sortGaps(delta.NewlyOutdated)
+	sortGaps(delta.Resolved)
+	return delta
+}
+
+// missingGaps returns the set of missing gaps in the given report.
+func missingGaps(r Report) map[Gap]bool {
+	gaps := map[Gap]bool{}
+	for _, gap := range r.Gaps() {
+		if gap.Kind == MissingGap {
+			gaps[gap] = true
+		}
+	}
+
+	return gaps
+}
+
+// scanRef checks out the given git ref of the repository containing 'dir' into a
+// temporary git worktree and scans the Android project at the same path in it. The
+// worktree is removed before returning.
+func scanRef(dir, ref string, opts Options) (Report, error) {
+	return withRef(dir, ref, func(refDir string) (Report, error) {
+		report, err := Scan(refDir, opts)
+		if err != nil {
+			return Report{}, errors.Wrapf(err, "unable to scan %s", ref)
+		}
+
+		return report, nil
+	})
+}
+
+// withRef checks out the given git ref of the repository containing 'dir' into a
+// temporary git worktree and calls 'fn' with the path of the Android project at the
+// same path in it. The worktree is removed before returning.
+func withRef(dir, ref string, fn func(refDir string) (Report, error)) (Report, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-prefix")
+	cmd.Dir = dir
+	prefix, err := cmd.Output()
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "unable to find the git repository of %s", dir)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "android-translations-")
+	if err != nil {
+		return Report{}, errors.Wrap(err, "unable to create temporary directory")
+	}
+
+	defer os.RemoveAll(tmpDir)
+	worktree := filepath.Join(tmpDir, "worktree")
+	cmd = exec.Command("git", "worktree", "add", "--detach", worktree, ref)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return Report{}, errors.Wrapf(err, "unable to check out %s: %s", ref, strings.TrimSpace(string(output)))
+	}
+
+	defer func() {
+		cmd := exec.Command("git", "worktree", "remove", "--force", worktree)
+		cmd.Dir = dir
+		cmd.Run()
+	}()
+
+	return fn(filepath.Join(worktree, strings.TrimSpace(string(prefix))))
+}
@@ -0,0 +1,148 @@
+package translations
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// commitValuesFiles writes the given contents of values files, keyed by their paths
+// relative to 'dir', removes the ones with empty contents and commits all changes
+// of 'dir' to its git repository, which is created if it doesn't exist yet.
+func commitValuesFiles(t *testing.T, dir string, files map[string]string) {
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		git("init", "-q")
+	}
+
+	for path, content := range files {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if content == "" {
+			if err := os.RemoveAll(filepath.Dir(path)); err != nil {
+				t.Fatal(err)
+			}
+
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("add", "-A")
+	git("commit", "-q", "-m", "Update strings")
+}
+
+func TestCompareRefs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is required to compare refs")
+	}
+
+	dir := writeValuesFiles(t, map[string]string{
+		"res/values/strings.xml":    `<resources><string name="hello">Hello</string><string name="bye">Bye</string></resources>`,
+		"res/values-de/strings.xml": `<resources><string name="hello">Hallo</string></resources>`,
+		"res/values-fr/strings.xml": `<resources><string name="hello">Bonjour</string><string name="bye">Au revoir</string></resources>`,
+	})
+
+	commitValuesFiles(t, dir, nil)
+	// adds a string and 'es', removes 'fr' and translates 'bye' to 'de'
+	commitValuesFiles(t, dir, map[string]string{
+		"res/values/strings.xml":    `<resources><string name="hello">Hello</string><string name="bye">Bye</string><string name="cancel">Cancel</string></resources>`,
+		"res/values-de/strings.xml": `<resources><string name="hello">Hallo</string><string name="bye">Tschüss</string></resources>`,
+		"res/values-es/strings.xml": `<resources><string name="hello">Hola</string></resources>`,
+		"res/values-fr/strings.xml": "",
+	})
+
+	delta, err := CompareRefs(dir, "HEAD~1", "HEAD", Options{SkipOutdated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if delta.OldRef != "HEAD~1" || delta.NewRef != "HEAD" {
+		t.Errorf("CompareRefs() refs = %q..%q, want %q..%q", delta.OldRef, delta.NewRef, "HEAD~1", "HEAD")
+	}
+
+	deCoverage, esCoverage := 100*2/3.0, 100/3.0
+	locales := []LocaleDelta{
+		{Locale: "de", OldCoverage: 50, NewCoverage: deCoverage, Change: deCoverage - 50},
+		{Locale: "es", OldCoverage: 0, NewCoverage: esCoverage, Change: esCoverage},
+		{Locale: "fr", OldCoverage: 100, NewCoverage: 0, Change: -100},
+	}
+
+	if !reflect.DeepEqual(delta.Locales, locales) {
+		t.Errorf("CompareRefs() locales = %+v, want %+v", delta.Locales, locales)
+	}
+
+	if want := []Gap{{Name: "bye", Locale: "de", Kind: MissingGap}}; !reflect.DeepEqual(delta.NewlyTranslated, want) {
+		t.Errorf("CompareRefs() newly translated = %+v, want %+v", delta.NewlyTranslated, want)
+	}
+
+	want := []Gap{
+		{Name: "bye", Locale: "es", Kind: MissingGap},
+		{Name: "cancel", Locale: "de", Kind: MissingGap},
+		{Name: "cancel", Locale: "es", Kind: MissingGap},
+	}
+
+	if !reflect.DeepEqual(delta.NewlyMissing, want) {
+		t.Errorf("CompareRefs() newly missing = %+v, want %+v", delta.NewlyMissing, want)
+	}
+}
+
+func TestCompareGaps(t *testing.T) {
+	previous := []Gap{
+		{Name: "bye", Locale: "de", Kind: MissingGap},       // unchanged
+		{Name: "hello", Locale: "fr", Kind: MissingGap},     // resolved
+		{Name: "hello", Locale: "de", Kind: OutdatedGap},    // resolved as outdated, but missing now
+		{Name: "settings", Locale: "es", Kind: OutdatedGap}, // resolved
+	}
+
+	current := []Gap{
+		{Name: "hello", Locale: "de", Kind: MissingGap},
+		{Name: "cancel", Locale: "fr", Kind: MissingGap},
+		{Name: "bye", Locale: "de", Kind: MissingGap},
+		{Name: "bye", Locale: "fr", Kind: OutdatedGap},
+	}
+
+	want := GapDelta{
+		NewlyMissing: []Gap{
+			{Name: "cancel", Locale: "fr", Kind: MissingGap},
+			{Name: "hello", Locale: "de", Kind: MissingGap},
+		},
+		NewlyOutdated: []Gap{{Name: "bye", Locale: "fr", Kind: OutdatedGap}},
+		Resolved: []Gap{
+			{Name: "hello", Locale: "de", Kind: OutdatedGap},
+			{Name: "hello", Locale: "fr", Kind: MissingGap},
+			{Name: "settings", Locale: "es", Kind: OutdatedGap},
+		},
+	}
+
+	if got := CompareGaps(previous, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareGaps() = %+v, want %+v", got, want)
+	}
+
+	// the delta of unchanged gaps is empty, but not nil for the JSON report
+	empty := GapDelta{NewlyMissing: []Gap{}, NewlyOutdated: []Gap{}, Resolved: []Gap{}}
+	if got := CompareGaps(current, current); !reflect.DeepEqual(got, empty) {
+		t.Errorf("CompareGaps() of the same gaps = %+v, want %+v", got, empty)
+	}
+
+	if got := CompareGaps(nil, nil); !reflect.DeepEqual(got, empty) {
+		t.Errorf("CompareGaps() without gaps = %+v, want %+v", got, empty)
+	}
+}
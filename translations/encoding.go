@@ -0,0 +1,70 @@
+package translations
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// xmlEncodingExpr matches the encoding declaration of an XML document, e.g.
+// '<?xml version="1.0" encoding="ISO-8859-1"?>'. The second group is the encoding.
+var xmlEncodingExpr = regexp.MustCompile(`^(\s*<\?xml[^>]*?\bencoding\s*=\s*["'])([A-Za-z0-9._:-]+)(["'])`)
+
+// decodeValuesFile converts the given values file content to UTF-8, so that it can
+// be parsed and searched for the line ranges of its elements like any other file.
+// The encoding is detected from the byte order mark, e.g. of the UTF-8 and UTF-16
+// files exported by some translation tools, or else from the XML declaration. The
+// encoding declaration is changed to UTF-8 along with the content since the XML
+// decoder rejects the documents that declare other encodings.
+func decodeValuesFile(content []byte) ([]byte, error) {
+	var enc encoding.Encoding
+	switch {
+	case bytes.HasPrefix(content, []byte{0xEF, 0xBB, 0xBF}):
+		content = content[3:]
+	case bytes.HasPrefix(content, []byte{0xFF, 0xFE}), bytes.HasPrefix(content, []byte{0xFE, 0xFF}):
+		enc = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM) // the BOM overrides the endianness
+	case bytes.HasPrefix(content, []byte{'<', 0}):
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case bytes.HasPrefix(content, []byte{0, '<'}):
+		enc = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+
+	if enc != nil {
+		decoded, err := enc.NewDecoder().Bytes(content)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to decode UTF-16 content")
+		}
+
+		content = decoded
+	}
+
+	match := xmlEncodingExpr.FindSubmatch(content)
+	if match == nil {
+		return content, nil
+	}
+
+	name := strings.ToLower(string(match[2]))
+	if name == "utf-8" {
+		return content, nil
+	}
+
+	// UTF-16 content is already decoded above, only its declaration is left to change
+	if enc == nil && !strings.HasPrefix(name, "utf-16") {
+		declared, err := htmlindex.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported encoding %q", match[2])
+		}
+
+		if content, err = declared.NewDecoder().Bytes(content); err != nil {
+			return nil, errors.Wrapf(err, "unable to decode %s content", match[2])
+		}
+	}
+
+	return xmlEncodingExpr.ReplaceAll(content, []byte("${1}UTF-8${3}")), nil
+}
@@ -0,0 +1,87 @@
+package translations
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const encodingTestXML = `<?xml version="1.0" encoding="%s"?>
+<resources>
+    <string name="greeting">Grüß dich, %%1$s</string>
+</resources>
+`
+
+// encodeUTF16 encodes the given string in UTF-16 with the given endianness and BOM
+// policy.
+func encodeUTF16(t *testing.T, s string, endianness unicode.Endianness, bom unicode.BOMPolicy) []byte {
+	encoded, err := unicode.UTF16(endianness, bom).NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return encoded
+}
+
+func TestDecodeValuesFile(t *testing.T) {
+	utf8XML := fmt.Sprintf(encodingTestXML, "UTF-8")
+	utf16XML := fmt.Sprintf(encodingTestXML, "UTF-16")
+	latin1XML, err := charmap.ISO8859_1.NewEncoder().Bytes([]byte(fmt.Sprintf(encodingTestXML, "ISO-8859-1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"UTF-8", []byte(utf8XML)},
+		{"UTF-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, utf8XML...)},
+		{"UTF-16LE with BOM", encodeUTF16(t, utf16XML, unicode.LittleEndian, unicode.UseBOM)},
+		{"UTF-16BE with BOM", encodeUTF16(t, utf16XML, unicode.BigEndian, unicode.UseBOM)},
+		{"UTF-16LE without BOM", encodeUTF16(t, utf16XML, unicode.LittleEndian, unicode.IgnoreBOM)},
+		{"UTF-16BE without BOM", encodeUTF16(t, utf16XML, unicode.BigEndian, unicode.IgnoreBOM)},
+		{"ISO-8859-1", latin1XML},
+	}
+
+	for _, test := range tests {
+		decoded, err := decodeValuesFile(test.content)
+		if err != nil {
+			t.Errorf("%s: decodeValuesFile() error = %v", test.name, err)
+			continue
+		}
+
+		if string(decoded) != utf8XML {
+			t.Errorf("%s: decodeValuesFile() = %q, want %q", test.name, decoded, utf8XML)
+		}
+	}
+}
+
+func TestDecodeValuesFileUnsupportedEncoding(t *testing.T) {
+	if _, err := decodeValuesFile([]byte(fmt.Sprintf(encodingTestXML, "x-unknown"))); err == nil {
+		t.Error("decodeValuesFile() error = nil, want unsupported encoding error")
+	}
+}
+
+func TestScanUTF16ValuesFile(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{
+		"res/values/strings.xml":    "<resources>\n    <string name=\"title\">Title</string>\n    <string name=\"greeting\">Hello, %1$s</string>\n</resources>\n",
+		"res/values-de/strings.xml": string(append([]byte{0xEF, 0xBB, 0xBF}, fmt.Sprintf(encodingTestXML, "UTF-8")...)),
+		"res/values-fr/strings.xml": string(encodeUTF16(t, fmt.Sprintf(encodingTestXML, "UTF-16"), unicode.LittleEndian, unicode.UseBOM)),
+	})
+
+	report, err := Scan(dir, Options{SkipOutdated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Strings) != 1 || report.Strings[0].Name != "title" {
+		t.Fatalf("Scan() strings = %v, want only 'title' missing", report.Strings)
+	}
+
+	if got := report.Strings[0].MissingLocales; len(got) != 2 || got[0] != "de" || got[1] != "fr" {
+		t.Errorf("Scan() missing locales of 'title' = %v, want [de fr]", got)
+	}
+}
@@ -0,0 +1,75 @@
+package translations
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// resourceReferenceExpr matches the values that are references to other resources
+// or theme attributes, e.g. '@string/app_name', '@android:string/ok' and
+// '?attr/colorPrimary'.
+var resourceReferenceExpr = regexp.MustCompile(`^([@?]\+?([\w.]+:)?[\w]+/[\w.]+|@null)$`)
+
+// findEscapeWarnings returns the Android string escaping problems of the given
+// strings in all locales, sorted by the string names and locales. See checkEscaping.
+func findEscapeWarnings(localeStrings localeStringsMap) []EscapeWarning {
+	warnings := make([]EscapeWarning, 0)
+	for locale, strs := range localeStrings {
+		for name, str := range strs {
+			for _, message := range checkEscaping(str.TrimmedValue()) {
+				warnings = append(warnings, EscapeWarning{Name: name, Locale: locale, Message: message})
+			}
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Name != warnings[j].Name {
+			return warnings[i].Name < warnings[j].Name
+		}
+
+		if warnings[i].Locale != warnings[j].Locale {
+			return warnings[i].Locale < warnings[j].Locale
+		}
+
+		return warnings[i].Message < warnings[j].Message
+	})
+
+	return warnings
+}
+
+// checkEscaping returns the messages for the problems in the given value that pass
+// XML parsing but fail the Android build, i.e. apostrophes that are neither escaped
+// nor inside double quotes, an unescaped '@' or '?' at the start of a value that
+// isn't a resource reference and a dangling backslash at the end.
+func checkEscaping(value string) []string {
+	messages := make([]string, 0)
+	if (strings.HasPrefix(value, "@") || strings.HasPrefix(value, "?")) && !resourceReferenceExpr.MatchString(value) {
+		messages = append(messages, fmt.Sprintf("unescaped %q at the start", value[0]))
+	}
+
+	escaped, quoted, apostrophes := false, false, 0
+	for _, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == '\'' && !quoted:
+			apostrophes++
+		}
+	}
+
+	if apostrophes > 0 {
+		messages = append(messages, fmt.Sprintf("%d unescaped apostrophe(s), use \\' or wrap the value in double quotes", apostrophes))
+	}
+
+	if escaped {
+		messages = append(messages, "dangling backslash at the end")
+	}
+
+	return messages
+}
@@ -0,0 +1,160 @@
+package translations
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// formatSpecifierExpr matches the 'java.util.Formatter' specifiers in string values,
+// e.g. '%s', '%1$d' and '%2$.2f'.
+var formatSpecifierExpr = regexp.MustCompile(`%(\d+\$)?[-#+ 0,(<]*\d*(\.\d+)?([tT]?[a-zA-Z%])`)
+
+// CheckFormat finds the values files in the Android project at 'dir' and checks the
+// strings of all locales for format specifier and Android string escaping problems
+// without looking at the git history, so that it is fast enough to run before each
+// commit. The format specifiers of each translation must match the ones of its
+// default string. The returned problems are sorted by their files and lines.
+func CheckFormat(dir string, opts Options) ([]ValidationError, error) {
+	s := &scanner{dir: dir, opts: opts, skipBlame: true}
+	localeStrings, _, _, err := s.findLocaleStrings()
+	if err != nil {
+		return nil, err
+	}
+
+	sourceLocale := getSourceLocale(opts)
+	defaultStrings := localeStrings[sourceLocale]
+	problems := make([]ValidationError, 0)
+	for _, locale := range localeStrings.sortedLocales() {
+		for name, str := range localeStrings[locale] {
+			messages := make([]string, 0)
+			for _, value := range itemValues(str) {
+				messages = append(messages, checkEscaping(value)...)
+			}
+
+			if locale != sourceLocale {
+				if message := checkFormatSpecifiers(defaultStrings, str); message != "" {
+					messages = append(messages, message)
+				}
+			}
+
+			for _, message := range messages {
+				problems = append(problems, ValidationError{
+					File:    s.relPath(str.File),
+					Line:    str.Line,
+					Message: fmt.Sprintf("%s [%s]: %s", name, locale, message),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+
+		return problems[i].Message < problems[j].Message
+	})
+
+	return problems, nil
+}
+
+// itemValues returns the trimmed values of the given string, i.e. the values of its
+// items if it is an atomic string array.
+func itemValues(str xmlStringResource) []string {
+	if str.Type != ArrayType {
+		return []string{str.TrimmedValue()}
+	}
+
+	values := make([]string, 0, len(str.Items))
+	for _, item := range str.Items {
+		values = append(values, item.TrimmedValue())
+	}
+
+	return values
+}
+
+// checkFormatSpecifiers returns the message for the mismatch between the format
+// specifiers of the given translation and its default string, or an empty string if
+// they match. Since plurals quantities differ between languages, e.g. the 'one'
+// quantity often leaves out the count, plurals items are only checked for the
+// specifiers that the 'other' default item doesn't have.
+func checkFormatSpecifiers(defaultStrings map[string]xmlStringResource, localeStr xmlStringResource) string {
+	if localeStr.Type == PluralItemType {
+		defaultStr, ok := defaultStrings[fmt.Sprintf("%s[other]", localeStr.Parent)]
+		if !ok {
+			return ""
+		}
+
+		expected := map[string]bool{}
+		for _, specifier := range formatSpecifiers(defaultStr.TrimmedValue()) {
+			expected[specifier] = true
+		}
+
+		for _, specifier := range formatSpecifiers(localeStr.TrimmedValue()) {
+			if !expected[specifier] {
+				return fmt.Sprintf("format specifier %s isn't in the default string", specifier)
+			}
+		}
+
+		return ""
+	}
+
+	defaultStr, ok := defaultStrings[localeStr.Name]
+	if !ok {
+		return ""
+	}
+
+	defaultValues, values := itemValues(defaultStr), itemValues(localeStr)
+	if len(defaultValues) != len(values) {
+		return "" // reported as missing by the scan
+	}
+
+	for i := range values {
+		expected, actual := formatSpecifiers(defaultValues[i]), formatSpecifiers(values[i])
+		if strings.Join(expected, ", ") != strings.Join(actual, ", ") {
+			return fmt.Sprintf("format specifiers [%s] don't match the default string [%s]",
+				strings.Join(actual, ", "), strings.Join(expected, ", "))
+		}
+	}
+
+	return ""
+}
+
+// formatSpecifiers returns the distinct format specifiers in the given value with
+// explicit argument indices, e.g. '%2$d' for the second '%d', in sorted order. Flags,
+// widths and precisions are left out as translations may change them. Literal '%%'
+// and '%n' don't take an argument, so they are left out too.
+func formatSpecifiers(value string) []string {
+	specifiers := make([]string, 0)
+	seen := map[string]bool{}
+	index, ordinaryIndex := 0, 0
+	for _, match := range formatSpecifierExpr.FindAllStringSubmatch(value, -1) {
+		conversion := match[3]
+		if conversion == "%" || conversion == "n" {
+			continue
+		}
+
+		if match[1] != "" {
+			index, _ = strconv.Atoi(strings.TrimSuffix(match[1], "$"))
+		} else if !strings.Contains(match[0], "<") { // '%<s' reuses the previous argument
+			ordinaryIndex++
+			index = ordinaryIndex
+		}
+
+		specifier := fmt.Sprintf("%%%d$%s", index, strings.ToLower(conversion))
+		if !seen[specifier] {
+			seen[specifier] = true
+			specifiers = append(specifiers, specifier)
+		}
+	}
+
+	sort.Strings(specifiers)
+	return specifiers
+}
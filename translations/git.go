@@ -0,0 +1,358 @@
+package translations
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// isGitIgnored checks if the given path is ignored from being tracked by 'git'. 'workingDir'
+// is used provide additional to 'git' command. It returns false, if 'workingDir' is not an
+// ancestor of the given file path.
+func isGitIgnored(workingDir, file string) bool {
+	relFilePath, err := filepath.Rel(workingDir, file)
+	if err != nil {
+		return false
+	}
+
+	cmd := exec.Command("git", "check-ignore", relFilePath)
+	cmd.Dir = workingDir
+	if err := cmd.Run(); err != nil {
+		return false
+	}
+
+	return true
+}
+
+// isShallowRepository checks if the git repository containing 'dir' is a shallow
+// clone. Git blame attributes all lines to the oldest available commit in shallow
+// clones, so the last modified times found with it are meaningless. It returns false
+// if 'dir' isn't inside a git repository.
+func isShallowRepository(dir string) bool {
+	cmd := exec.Command("git", "rev-parse", "--is-shallow-repository")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(string(output)) == "true"
+}
+
+// getHead returns the commit hash of the git HEAD of the repository containing 'dir'.
+func getHead(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to find git HEAD of %s", dir)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// isWorkingTreeClean checks if 'dir' has neither uncommitted changes nor untracked
+// files that aren't ignored. It returns false if 'dir' isn't inside a git
+// repository.
+func isWorkingTreeClean(dir string) bool {
+	cmd := exec.Command("git", "status", "--porcelain", "--", ".")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+
+	return len(bytes.TrimSpace(output)) == 0
+}
+
+// Styles of the git hosts for the source URLs of the strings.
+const (
+	GitHubHostStyle = "github" // '<repo-url>/blob/<ref>/<path>#L<line>'
+	GitLabHostStyle = "gitlab" // '<repo-url>/-/blob/<ref>/<path>#L<line>'
+)
+
+// sourceURLBuilder builds the URLs of the strings on the git host.
+type sourceURLBuilder struct {
+	base   string // repository URL followed by the blob path and the ref
+	prefix string // path of the project directory relative to the repository root
+}
+
+// newSourceURLBuilder returns a sourceURLBuilder for the git repository containing
+// 'dir' using the repository URL, ref and host style in the given options. If the
+// ref isn't set, the commit currently checked out is used.
+func newSourceURLBuilder(dir string, opts Options) (*sourceURLBuilder, error) {
+	ref := opts.GitRef
+	if ref == "" {
+		cmd := exec.Command("git", "rev-parse", "HEAD")
+		cmd.Dir = dir
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to find the current commit for source URLs")
+		}
+
+		ref = strings.TrimSpace(string(output))
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--show-prefix")
+	cmd.Dir = dir
+	prefix, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find the project directory in its git repository")
+	}
+
+	blobPath := "blob"
+	if opts.HostStyle == GitLabHostStyle {
+		blobPath = "-/blob"
+	}
+
+	return &sourceURLBuilder{
+		base:   fmt.Sprintf("%s/%s/%s/", strings.TrimSuffix(opts.RepoURL, "/"), blobPath, ref),
+		prefix: strings.TrimSpace(string(prefix)),
+	}, nil
+}
+
+// url returns the URL of the given line in the given file, relative to the project
+// directory. It returns an empty string for the files inside archives since they
+// can't be linked.
+func (b *sourceURLBuilder) url(file string, line int) string {
+	if isArchiveEntry(file) {
+		return ""
+	}
+
+	url := b.base + b.prefix + file
+	if line > 0 {
+		url += fmt.Sprintf("#L%d", line)
+	}
+
+	return url
+}
+
+// blameHeaderExpr matches the header lines of 'git blame -p' output, i.e. the commit
+// hash followed by the original and the final line numbers.
+var blameHeaderExpr = regexp.MustCompile(`^([0-9a-f]{40,64}) \d+ \d+`)
+
+// uncommittedHashExpr matches the hash that 'git blame' uses for uncommitted lines.
+var uncommittedHashExpr = regexp.MustCompile(`^0+$`)
+
+// getLastModified returns the last modified time of the given line range in the
+// given file, the name of its committer and the commit using 'git blame'. The
+// commit is empty if the last modification isn't committed yet.
+func getLastModified(file string, lineStart, lineCount int) (time.Time, string, Commit, error) {
+	const errFmt = "unable to find last modified time, file: %q, start: %d, count: %d"
+
+	lineRange := fmt.Sprintf("%d,+%d", lineStart, lineCount)
+	cmd := exec.Command("git", "blame", "-p", "-L", lineRange, filepath.Base(file))
+	cmd.Dir = filepath.Dir(file)
+	output, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, "", Commit{}, errors.Wrapf(err, errFmt, file, lineStart, lineCount)
+	}
+
+	// should handle case where multiline blame returns multiple commits and thus
+	// multiple committer, committer-time and summary fields. The fields of a commit
+	// follow its first header line and the 'committer' field always precedes the
+	// 'committer-time' field of the same commit.
+	var latestTimestamp int64
+	var hash, committer, latestCommitter, latestHash string
+	summaries := map[string]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "\t") { // content of the blamed line
+			continue
+		}
+
+		if match := blameHeaderExpr.FindStringSubmatch(line); match != nil {
+			hash = match[1]
+			continue
+		}
+
+		if strings.HasPrefix(line, "committer ") {
+			committer = strings.TrimPrefix(line, "committer ")
+			continue
+		}
+
+		if strings.HasPrefix(line, "summary ") {
+			summaries[hash] = strings.TrimPrefix(line, "summary ")
+			continue
+		}
+
+		if !strings.HasPrefix(line, "committer-time ") {
+			continue
+		}
+
+		timestamp, err := strconv.ParseInt(strings.TrimPrefix(line, "committer-time "), 10, 64)
+		if err != nil {
+			return time.Time{}, "", Commit{}, errors.Wrapf(err, errFmt, file, lineStart, lineCount)
+		}
+
+		if timestamp > latestTimestamp {
+			latestTimestamp = timestamp
+			latestCommitter = committer
+			latestHash = hash
+		}
+	}
+
+	if latestTimestamp == 0 {
+		return time.Time{}, "", Commit{}, fmt.Errorf(errFmt, file, lineStart, lineCount)
+	}
+
+	var commit Commit
+	if !uncommittedHashExpr.MatchString(latestHash) {
+		commit = Commit{Hash: latestHash, Summary: summaries[latestHash]}
+	}
+
+	return time.Unix(latestTimestamp, 0), latestCommitter, commit, nil
+}
+
+// findOutdatedDiffs returns the value-level changes for each potentially outdated
+// translation in the given strings. The previous default value is the value at the
+// time the translation was last modified, if it can be found using git.
+func (s *scanner) findOutdatedDiffs(
+	strs []StringResource,
+	defaultStrings map[string]xmlStringResource,
+	localeStrings localeStringsMap,
+) []OutdatedDiff {
+	diffs := make([]OutdatedDiff, 0)
+	for _, res := range strs {
+		defaultStr := defaultStrings[res.Name]
+		for _, locale := range res.OutdatedLocales {
+			localeStr := localeStrings[locale][res.Name]
+			previous, err := getHistoricalValue(defaultStr.File, res.Name, localeStr.LastModified)
+			if err != nil {
+				s.warn(err)
+			}
+
+			diffs = append(diffs, OutdatedDiff{
+				Name:          res.Name,
+				Locale:        locale,
+				Translation:   localeStr.TrimmedValue(),
+				PreviousValue: previous,
+				CurrentValue:  res.Value,
+			})
+		}
+	}
+
+	return diffs
+}
+
+// getHistoricalValue returns the value of the string with the given name in the
+// given file as of the last commit at or before the given time.
+func getHistoricalValue(file, name string, at time.Time) (string, error) {
+	const errFmt = "unable to find previous value, file: %q, name: %q"
+	resources, err := getHistoricalResources(file, at)
+	if err != nil {
+		return "", errors.Wrapf(err, errFmt, file, name)
+	}
+
+	value, ok := findResourceValue(resources, name)
+	if !ok {
+		return "", fmt.Errorf(errFmt, file, name)
+	}
+
+	return value, nil
+}
+
+// getHistoricalResources returns the resources in the given file as of the last
+// commit at or before the given time.
+func getHistoricalResources(file string, at time.Time) (*xmlStringResources, error) {
+	if isArchiveEntry(file) {
+		return nil, errors.New("git history isn't available for archives")
+	}
+
+	logCmd := exec.Command("git", "log", "-1", "--format=%H", fmt.Sprintf("--before=@%d", at.Unix()), "--", filepath.Base(file))
+	logCmd.Dir = filepath.Dir(file)
+	hash, err := logCmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(hash)) == 0 {
+		return nil, errors.New("no commit found")
+	}
+
+	showCmd := exec.Command("git", "show", fmt.Sprintf("%s:./%s", bytes.TrimSpace(hash), filepath.Base(file)))
+	showCmd.Dir = filepath.Dir(file)
+	content, err := showCmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	if content, err = decodeValuesFile(content); err != nil {
+		return nil, err
+	}
+
+	resources := &xmlStringResources{}
+	if err := xml.Unmarshal(content, resources); err != nil {
+		return nil, err
+	}
+
+	fillTextValues(resources)
+	return resources, nil
+}
+
+// findResourceValue returns the value of the string with the given name in the
+// given resources. The name may also be of an item of a string array, e.g.
+// 'name[0]', of an atomic string array or of a plurals item, e.g. 'name[one]'.
+func findResourceValue(resources *xmlStringResources, name string) (string, bool) {
+	for _, str := range resources.Strings {
+		if str.Name == name {
+			return str.TrimmedValue(), true
+		}
+	}
+
+	for _, strArr := range resources.StringArrays {
+		if strArr.Name == name { // atomic string array
+			values := make([]string, 0, len(strArr.Items))
+			for _, item := range strArr.Items {
+				values = append(values, item.TrimmedValue())
+			}
+
+			return strings.Join(values, "\n"), true
+		}
+
+		for i, item := range strArr.Items {
+			if fmt.Sprintf("%s[%d]", strArr.Name, i) == name {
+				return item.TrimmedValue(), true
+			}
+		}
+	}
+
+	for _, plurals := range resources.Plurals {
+		for _, item := range plurals.Items {
+			if fmt.Sprintf("%s[%s]", plurals.Name, item.Quantity) == name {
+				return item.TrimmedValue(), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// isValueUnchanged checks if the value of the given default string is the same as
+// it was at the given time, ignoring differences in whitespace. It is used to tell
+// apart the translations that were only outdated by reformatting of the default
+// values file. The historical resources are cached by the file and time since the
+// translations of a locale are usually modified together. It returns false if the
+// historical value can't be found.
+func (s *scanner) isValueUnchanged(str xmlStringResource, at time.Time) bool {
+	key := fmt.Sprintf("%s@%d", str.File, at.Unix())
+	resources, ok := s.history[key]
+	if !ok {
+		resources, _ = getHistoricalResources(str.File, at)
+		s.history[key] = resources
+	}
+
+	if resources == nil {
+		return false
+	}
+
+	previous, ok := findResourceValue(resources, str.Name)
+	return ok && strings.Join(strings.Fields(previous), " ") == strings.Join(strings.Fields(str.TrimmedValue()), " ")
+}
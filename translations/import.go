@@ -0,0 +1,554 @@
+package translations
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// emptyValuesFile is the content of the values files created by ImportTranslations.
+const emptyValuesFile = "<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<resources>\n</resources>\n"
+
+// ImportReport declares the output structure for the translations written back to
+// the values files of a locale by ImportTranslations.
+type ImportReport struct {
+	Locale   string   `json:"locale"`
+	Files    []string `json:"files"`   // sorted values files that were written
+	Updated  []string `json:"updated"` // names of the translations that replaced different existing ones
+	Added    []string `json:"added"`   // names of the translations that were added
+	Skipped  []string `json:"skipped"` // names of the translations that weren't imported, see Warnings
+	Warnings []string `json:"-"`
+}
+
+// ReadPO reads the messages of the gettext PO file at the given path, e.g. one
+// exported by ExportPO and translated in a translation management system. The
+// header entry is left out.
+func ReadPO(path string) ([]POEntry, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read file at %s", path)
+	}
+
+	entries := make([]POEntry, 0)
+	var entry POEntry
+	var target *string // string of the entry that continuation lines are appended to
+	flush := func() {
+		if entry.ID != "" || entry.Context != "" {
+			entries = append(entries, entry)
+		}
+
+		entry, target = POEntry{}, nil
+	}
+
+	lines := bufio.NewScanner(bytes.NewReader(content))
+	lines.Buffer(make([]byte, 0, 64*1024), 1024*1024) // values may be long
+	for lineNo := 1; lines.Scan(); lineNo++ {
+		line := strings.TrimSpace(lines.Text())
+		switch {
+		case line == "":
+			flush()
+			continue
+		case strings.HasPrefix(line, "#,"):
+			entry.Fuzzy = entry.Fuzzy || strings.Contains(line, "fuzzy")
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, `"`):
+			if target == nil {
+				return nil, fmt.Errorf("%s:%d: string without a keyword", path, lineNo)
+			}
+
+			value, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid string %s", path, lineNo, line)
+			}
+
+			*target += value
+			continue
+		}
+
+		split := strings.SplitN(line, " ", 2)
+		if len(split) != 2 {
+			return nil, fmt.Errorf("%s:%d: invalid line %q", path, lineNo, line)
+		}
+
+		value, err := strconv.Unquote(strings.TrimSpace(split[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid string %s", path, lineNo, split[1])
+		}
+
+		switch keyword := split[0]; {
+		case keyword == "msgctxt":
+			if entry.ID != "" || entry.Str != nil { // entries without a blank line in between
+				flush()
+			}
+
+			entry.Context, target = value, &entry.Context
+		case keyword == "msgid":
+			entry.ID, target = value, &entry.ID
+		case keyword == "msgid_plural":
+			entry.IDPlural, target = value, &entry.IDPlural
+		case keyword == "msgstr" || strings.HasPrefix(keyword, "msgstr["):
+			entry.Str = append(entry.Str, value)
+			target = &entry.Str[len(entry.Str)-1]
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown keyword %q", path, lineNo, keyword)
+		}
+	}
+
+	if err := lines.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read file at %s", path)
+	}
+
+	flush()
+	return entries, nil
+}
+
+// ReadTranslationsJSON reads the JSON object of string names mapped to their
+// translations at the given path, e.g. '{"hello": "Hallo", "planets[0]": "Erde",
+// "songs[one]": "%d Lied"}', as messages for ImportTranslations.
+func ReadTranslationsJSON(path string) ([]POEntry, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read file at %s", path)
+	}
+
+	values := map[string]string{}
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse JSON file at %s", path)
+	}
+
+	entries := make([]POEntry, 0, len(values))
+	for name, value := range values {
+		entries = append(entries, POEntry{Context: name, Str: []string{value}})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Context < entries[j].Context })
+	return entries, nil
+}
+
+// ImportTranslations writes the translations of the given messages back to the
+// values files of the given locale qualifier, e.g. 'fr' or 'b+sr+Latn', in the
+// Android project at 'dir'. The context of each message is the name of its default
+// string, as exported by ExportPO. Existing translations are replaced in place,
+// keeping their attributes, and new ones are appended to 'values-<locale>/strings.xml'
+// next to the default strings, in the order of the default strings, creating it if
+// needed. Plural messages are mapped to the CLDR plural quantities of the locale,
+// like ExportPO does, or if they aren't known, to the quantities that the locale
+// already defines, or else to the default quantities. Empty and fuzzy translations are
+// skipped, and so are the translations that would replace existing ones with markup,
+// e.g. '<xliff:g>', since the imported values are plain text. The values are escaped
+// as per the Android string resource rules. Existing translations that don't change
+// aren't reported as updated.
+func ImportTranslations(dir, locale string, entries []POEntry, opts Options) (ImportReport, error) {
+	opts.ArraysAtomic = false // items are messages on their own
+	s := &scanner{dir: dir, opts: opts, skipBlame: true}
+	localeStrings, _, _, err := s.findLocaleStrings()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	sourceLocale := getSourceLocale(opts)
+	report := ImportReport{
+		Locale:  canonicalLocale(locale),
+		Files:   make([]string, 0),
+		Updated: make([]string, 0),
+		Added:   make([]string, 0),
+		Skipped: make([]string, 0),
+	}
+
+	if report.Locale == sourceLocale {
+		return ImportReport{}, fmt.Errorf("locale %q is the default locale, which has no translations", locale)
+	}
+
+	defaultStrings, translated := localeStrings[sourceLocale], localeStrings[report.Locale]
+	skip := func(name, format string, args ...interface{}) {
+		report.Skipped = append(report.Skipped, name)
+		s.warnf(format, args...)
+	}
+
+	values := map[string]string{} // default string name => translation
+	for _, entry := range entries {
+		switch {
+		case entry.Fuzzy:
+			skip(entry.Context, "skipping fuzzy translation of %q", entry.Context)
+		case entry.IDPlural != "":
+			quantities := importQuantities(entry.Context, report.Locale, defaultStrings, translated)
+			for i, str := range entry.Str {
+				if i >= len(quantities) {
+					skip(entry.Context, "skipping plural form %d of %q, which has only %d quantities", i, entry.Context, len(quantities))
+				} else if str != "" {
+					values[fmt.Sprintf("%s[%s]", entry.Context, quantities[i])] = str
+				}
+			}
+		case len(entry.Str) > 0 && entry.Str[0] != "":
+			values[entry.Context] = entry.Str[0]
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if str, ok := defaultStrings[name]; !ok {
+			skip(name, "skipping translation of %q, which isn't a translatable default string", name)
+		} else if isArchiveEntry(str.File) {
+			skip(name, "skipping translation of %q, which is defined in an archive", name)
+		} else {
+			names = append(names, name)
+		}
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := defaultStrings[names[i]], defaultStrings[names[j]]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+
+		return names[i] < names[j]
+	})
+
+	w := &valuesWriter{files: map[string][]byte{}}
+	parentFiles := map[string]string{} // '<string-array>' or '<plurals>' name => file that translates it
+	for _, str := range translated {
+		if str.Parent != "" && !isArchiveEntry(str.File) {
+			parentFiles[str.Parent] = str.File
+		}
+	}
+
+	added := map[string]bool{} // names of the arrays and plurals added as a whole
+	for _, name := range names {
+		str, value := defaultStrings[name], escapeAndroidValue(values[name])
+		if localeStr, ok := translated[name]; ok && !isArchiveEntry(localeStr.File) {
+			if localeStr.TrimmedValue() == values[name] { // as exported by ExportPO
+				continue
+			}
+
+			// the imported values are plain text, so the markup would be lost
+			if strings.Contains(localeStr.InnerXML, "<") {
+				skip(name, "skipping translation of %q, whose existing translation has markup, e.g. <xliff:g>, that the import would lose", name)
+				continue
+			}
+
+			changed, err := w.replace(localeStr, value)
+			if err != nil {
+				return ImportReport{}, err
+			}
+
+			if changed {
+				report.Updated = append(report.Updated, name)
+			}
+
+			continue
+		}
+
+		target := filepath.Join(filepath.Dir(filepath.Dir(str.File)), "values-"+locale, "strings.xml")
+		var element string
+		switch {
+		case str.Type == StringType:
+			element = fmt.Sprintf(`<string name="%s">%s</string>`, str.Name, value)
+		case added[str.Parent]:
+			report.Added = append(report.Added, name)
+			continue
+		case parentFiles[str.Parent] != "":
+			if err := w.appendItem(parentFiles[str.Parent], str, value); err != nil {
+				skip(name, "skipping translation of %q: %s", name, err)
+				continue
+			}
+
+			report.Added = append(report.Added, name)
+			continue
+		default:
+			element = parentElement(str, defaultStrings, values)
+			if element == "" {
+				skip(name, "skipping translation of %q, as the other items of %q aren't translated", name, str.Parent)
+				continue
+			}
+
+			added[str.Parent] = true
+		}
+
+		if err := w.insert(target, element); err != nil {
+			return ImportReport{}, err
+		}
+
+		report.Added = append(report.Added, name)
+	}
+
+	for file, content := range w.files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return ImportReport{}, errors.Wrapf(err, "unable to create directory %s", filepath.Dir(file))
+		}
+
+		if err := ioutil.WriteFile(file, content, 0644); err != nil {
+			return ImportReport{}, errors.Wrapf(err, "unable to write file %s", file)
+		}
+
+		report.Files = append(report.Files, s.relPath(file))
+	}
+
+	sort.Strings(report.Files)
+	sort.Strings(report.Skipped)
+	report.Warnings = s.warnings
+	return report, nil
+}
+
+// importQuantities returns the CLDR plural quantities of the given locale, or if
+// they aren't known, the quantities, in the CLDR order, of the '<plurals>' with the
+// given name in the translated strings, or else in the default strings.
+func importQuantities(name, locale string, defaultStrings, translated map[string]xmlStringResource) []string {
+	if forms, ok := lookupPluralForms(poLanguage(locale)); ok {
+		return forms.Quantities
+	}
+
+	for _, strs := range []map[string]xmlStringResource{translated, defaultStrings} {
+		quantities := make([]string, 0)
+		for _, quantity := range pluralQuantities {
+			if _, ok := strs[fmt.Sprintf("%s[%s]", name, quantity)]; ok {
+				quantities = append(quantities, quantity)
+			}
+		}
+
+		if len(quantities) > 0 {
+			return quantities
+		}
+	}
+
+	return nil
+}
+
+// parentElement returns the '<string-array>' or '<plurals>' element of the given
+// default item with the imported values of its items. String arrays need a value
+// for each default item, since the items are matched by their positions. It
+// returns an empty string if a string array item is missing.
+func parentElement(str xmlStringResource, defaultStrings map[string]xmlStringResource, values map[string]string) string {
+	var items []string
+	if str.Type == PluralItemType {
+		for _, quantity := range pluralQuantities {
+			if value, ok := values[fmt.Sprintf("%s[%s]", str.Parent, quantity)]; ok {
+				items = append(items, fmt.Sprintf(`<item quantity="%s">%s</item>`, quantity, escapeAndroidValue(value)))
+			}
+		}
+
+		return fmt.Sprintf("<plurals name=\"%s\">\n        %s\n    </plurals>", str.Parent, strings.Join(items, "\n        "))
+	}
+
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s[%d]", str.Parent, i)
+		if _, ok := defaultStrings[name]; !ok {
+			break
+		}
+
+		value, ok := values[name]
+		if !ok {
+			return ""
+		}
+
+		items = append(items, fmt.Sprintf("<item>%s</item>", escapeAndroidValue(value)))
+	}
+
+	return fmt.Sprintf("<string-array name=\"%s\">\n        %s\n    </string-array>", str.Parent, strings.Join(items, "\n        "))
+}
+
+// escapeAndroidValue escapes the given text for a string resource value as per the
+// Android rules, i.e. the apostrophes and double quotes with backslashes, '<' and
+// '&' as XML entities and a leading '@' or '?' with a backslash. The existing
+// backslash escapes, e.g. `\n` and `\'`, are kept as is.
+func escapeAndroidValue(value string) string {
+	var escaped strings.Builder
+	if strings.HasPrefix(value, "@") || strings.HasPrefix(value, "?") {
+		escaped.WriteByte('\\')
+	}
+
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\':
+			if i+1 < len(value) {
+				escaped.WriteString(value[i : i+2])
+				i++
+			} else {
+				escaped.WriteString(`\\`)
+			}
+		case '\'', '"':
+			escaped.WriteByte('\\')
+			escaped.WriteByte(c)
+		case '<':
+			escaped.WriteString("&lt;")
+		case '&':
+			escaped.WriteString("&amp;")
+		default:
+			escaped.WriteByte(c)
+		}
+	}
+
+	return escaped.String()
+}
+
+// valuesWriter holds the contents of the values files modified by
+// ImportTranslations until they are written.
+type valuesWriter struct {
+	files map[string][]byte
+}
+
+// content returns the current content of the given values file. Files that don't
+// exist yet start out empty.
+func (w *valuesWriter) content(file string) ([]byte, error) {
+	if content, ok := w.files[file]; ok {
+		return content, nil
+	}
+
+	content, err := readValuesFile(file)
+	if os.IsNotExist(errors.Cause(err)) {
+		content, err = []byte(emptyValuesFile), nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read file at %s", file)
+	}
+
+	return content, nil
+}
+
+// replace replaces the value of the given translation in its values file. It returns
+// false and leaves the file as is if the value, apart from the surrounding
+// whitespace, doesn't change.
+func (w *valuesWriter) replace(localeStr xmlStringResource, value string) (bool, error) {
+	content, err := w.content(localeStr.File)
+	if err != nil {
+		return false, err
+	}
+
+	var start, end int
+	switch localeStr.Type {
+	case ArrayItemType:
+		start, end, err = findItem(content, "string-array", localeStr.Parent, itemIndex(localeStr))
+	case PluralItemType:
+		start, end, err = findPluralsItem(content, localeStr.Parent, localeStr.Quantity)
+	default:
+		start, end, err = findElement(content, "string", localeStr.Name)
+	}
+
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to update %s", localeStr.File)
+	}
+
+	element := content[start:end]
+	openingEnd := bytes.IndexByte(element, '>') + 1
+	closingStart := bytes.LastIndex(element, []byte("</"))
+	var replaced []byte
+	if bytes.HasSuffix(element, []byte("/>")) { // self-closing, e.g. '<string name="foo"/>'
+		tag := element[1:bytes.IndexAny(element, " \t\r\n/")]
+		opening := bytes.TrimRight(element[:len(element)-2], " \t\r\n")
+		replaced = []byte(fmt.Sprintf("%s>%s</%s>", opening, value, tag))
+	} else if string(bytes.TrimSpace(element[openingEnd:closingStart])) == value {
+		return false, nil
+	} else {
+		replaced = append(append(append([]byte{}, element[:openingEnd]...), value...), element[closingStart:]...)
+	}
+
+	w.files[localeStr.File] = append(append(append([]byte{}, content[:start]...), replaced...), content[end:]...)
+	return true, nil
+}
+
+// insert appends the given element to the '<resources>' of the given values file.
+func (w *valuesWriter) insert(file, element string) error {
+	content, err := w.content(file)
+	if err != nil {
+		return err
+	}
+
+	content, err = insertBefore(content, "</resources>", "    "+element)
+	if err != nil {
+		return errors.Wrapf(err, "unable to update %s", file)
+	}
+
+	w.files[file] = content
+	return nil
+}
+
+// appendItem appends the given default item with the given value to the
+// '<string-array>' or '<plurals>' of the given values file. String array items can
+// only be appended at the position of their default items.
+func (w *valuesWriter) appendItem(file string, str xmlStringResource, value string) error {
+	content, err := w.content(file)
+	if err != nil {
+		return err
+	}
+
+	tag, item := "plurals", fmt.Sprintf(`<item quantity="%s">%s</item>`, str.Quantity, value)
+	if str.Type == ArrayItemType {
+		if index := itemIndex(str); index > 0 {
+			if _, _, err := findItem(content, "string-array", str.Parent, index-1); err != nil {
+				return fmt.Errorf("item %d of %q is missing in %s", index-1, str.Parent, file)
+			}
+		}
+
+		tag, item = "string-array", fmt.Sprintf("<item>%s</item>", value)
+	}
+
+	start, end, err := findElement(content, tag, str.Parent)
+	if err != nil {
+		return errors.Wrapf(err, "unable to update %s", file)
+	}
+
+	element, err := insertBefore(content[start:end], "</"+tag+">", "        "+item)
+	if err != nil {
+		return errors.Wrapf(err, "unable to update %s", file)
+	}
+
+	w.files[file] = append(append(append([]byte{}, content[:start]...), element...), content[end:]...)
+	return nil
+}
+
+// insertBefore inserts the given line before the last occurrence of the given
+// closing tag in 'content'. If the closing tag is on a line of its own, the line
+// is inserted above it.
+func insertBefore(content []byte, closing, line string) ([]byte, error) {
+	i := bytes.LastIndex(content, []byte(closing))
+	if i < 0 {
+		return nil, fmt.Errorf("closing tag %s is not found", closing)
+	}
+
+	lineStart := bytes.LastIndexByte(content[:i], '\n') + 1
+	insertion := "\n" + line + "\n"
+	if len(bytes.TrimSpace(content[lineStart:i])) == 0 {
+		i, insertion = lineStart, line+"\n"
+	}
+
+	return append(append(append([]byte{}, content[:i]...), insertion...), content[i:]...), nil
+}
+
+// itemIndex returns the index of the given string array item, e.g. 1 for
+// 'planets[1]'.
+func itemIndex(str xmlStringResource) int {
+	index, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(str.Name, str.Parent+"["), "]"))
+	return index
+}
+
+// findPluralsItem returns the start and end offsets of the item with the given
+// quantity in the '<plurals>' element with the given name.
+func findPluralsItem(fileContent []byte, name, quantity string) (int, int, error) {
+	quantityExpr := regexp.MustCompile(fmt.Sprintf(`^<item\s[^>]*?quantity\s*=\s*"%s"`, regexp.QuoteMeta(quantity)))
+	for index := 0; ; index++ {
+		start, end, err := findItem(fileContent, "plurals", name, index)
+		if err != nil {
+			return 0, 0, fmt.Errorf("item %q of <plurals name=%q> is not found", quantity, name)
+		}
+
+		if quantityExpr.Match(fileContent[start:end]) {
+			return start, end, nil
+		}
+	}
+}
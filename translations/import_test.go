@@ -0,0 +1,212 @@
+package translations
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEscapeAndroidValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Hello", "Hello"},
+		{"Don't", `Don\'t`},
+		{`Say "hi"`, `Say \"hi\"`},
+		{"a < b", "a &lt; b"},
+		{"Tom & Jerry", "Tom &amp; Jerry"},
+		{"@string/app_name", `\@string/app_name`},
+		{"?attr/color", `\?attr/color`},
+		{"a@b?", "a@b?"},
+		{`Line\nbreak`, `Line\nbreak`},
+		{`Already \'escaped\'`, `Already \'escaped\'`},
+		{`trailing\`, `trailing\\`},
+	}
+
+	for _, test := range tests {
+		if got := escapeAndroidValue(test.value); got != test.want {
+			t.Errorf("escapeAndroidValue(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestInsertBefore(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"<resources>\n</resources>\n", "<resources>\n    <x/>\n</resources>\n"},
+		{"<resources>\n    <a/>\n    </resources>", "<resources>\n    <a/>\n    <x/>\n    </resources>"},
+		{"<resources><a/></resources>", "<resources><a/>\n    <x/>\n</resources>"},
+		{"<resources>\n</resources>\n<!-- </resources> -->\n</resources>", "<resources>\n</resources>\n<!-- </resources> -->\n    <x/>\n</resources>"},
+	}
+
+	for _, test := range tests {
+		got, err := insertBefore([]byte(test.content), "</resources>", "    <x/>")
+		if err != nil {
+			t.Errorf("insertBefore(%q) error = %v", test.content, err)
+		} else if string(got) != test.want {
+			t.Errorf("insertBefore(%q) = %q, want %q", test.content, got, test.want)
+		}
+	}
+
+	if _, err := insertBefore([]byte("<resources/>"), "</resources>", "<x/>"); err == nil {
+		t.Error("insertBefore() without the closing tag error = nil, want an error")
+	}
+}
+
+// importTestDefaultStrings is the default values file of the import tests.
+const importTestDefaultStrings = `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <string name="title">Title</string>
+    <string name="count">You have <xliff:g id="n">%d</xliff:g> items</string>
+    <string name="hello">Hello</string>
+    <string name="bye">Bye</string>
+    <string-array name="planets">
+        <item>Earth</item>
+        <item>Mars</item>
+    </string-array>
+    <plurals name="songs">
+        <item quantity="one">%d song</item>
+        <item quantity="other">%d songs</item>
+    </plurals>
+</resources>
+`
+
+func TestImportTranslationsExistingFile(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{
+		"res/values/strings.xml": importTestDefaultStrings,
+		"res/values-de/strings.xml": `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <string name="title">Titel</string>
+    <string name="count">Du hast <xliff:g id="n">%d</xliff:g> Dinge</string>
+    <string name="hello"/>
+    <string-array name="planets">
+        <item>Erde</item>
+    </string-array>
+    <plurals name="songs">
+        <item quantity="one">%d Lied</item>
+    </plurals>
+</resources>
+`,
+	})
+
+	entries := []POEntry{
+		{Context: "title", Str: []string{"Titel"}},
+		{Context: "count", Str: []string{"Du hast %d Sachen"}},
+		{Context: "hello", Str: []string{"Hallo"}},
+		{Context: "bye", Str: []string{"Tschüss"}},
+		{Context: "planets[1]", Str: []string{"Mars"}},
+		{Context: "songs[other]", Str: []string{"%d Lieder"}},
+	}
+
+	report, err := ImportTranslations(dir, "de", entries, Options{SkipOutdated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ImportReport{
+		Locale:   "de",
+		Files:    []string{filepath.Join("res", "values-de", "strings.xml")},
+		Updated:  []string{"hello"},
+		Added:    []string{"bye", "planets[1]", "songs[other]"},
+		Skipped:  []string{"count"},
+		Warnings: report.Warnings,
+	}
+
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("ImportTranslations() = %+v, want %+v", report, want)
+	}
+
+	assertFileContent(t, filepath.Join(dir, "res", "values-de", "strings.xml"), `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <string name="title">Titel</string>
+    <string name="count">Du hast <xliff:g id="n">%d</xliff:g> Dinge</string>
+    <string name="hello">Hallo</string>
+    <string-array name="planets">
+        <item>Erde</item>
+        <item>Mars</item>
+    </string-array>
+    <plurals name="songs">
+        <item quantity="one">%d Lied</item>
+        <item quantity="other">%d Lieder</item>
+    </plurals>
+    <string name="bye">Tschüss</string>
+</resources>
+`)
+}
+
+func TestImportTranslationsNewFile(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{"res/values/strings.xml": importTestDefaultStrings})
+	entries := []POEntry{
+		{Context: "songs[one]", Str: []string{"%d chanson"}},
+		{Context: "bye", Str: []string{"Au revoir"}},
+		{Context: "planets[1]", Str: []string{"Mars"}},
+		{Context: "hello", Str: []string{"Bonjour l'ami"}},
+		{Context: "planets[0]", Str: []string{"Terre"}},
+		{Context: "title", Str: []string{""}},
+	}
+
+	report, err := ImportTranslations(dir, "fr", entries, Options{SkipOutdated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"hello", "bye", "planets[0]", "planets[1]", "songs[one]"}; !reflect.DeepEqual(report.Added, want) {
+		t.Errorf("ImportTranslations() added = %v, want %v", report.Added, want)
+	}
+
+	assertFileContent(t, filepath.Join(dir, "res", "values-fr", "strings.xml"), `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <string name="hello">Bonjour l\'ami</string>
+    <string name="bye">Au revoir</string>
+    <string-array name="planets">
+        <item>Terre</item>
+        <item>Mars</item>
+    </string-array>
+    <plurals name="songs">
+        <item quantity="one">%d chanson</item>
+    </plurals>
+</resources>
+`)
+}
+
+func TestImportTranslationsUnchanged(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{
+		"res/values/strings.xml": importTestDefaultStrings,
+		"res/values-de/strings.xml": `<resources>
+    <string name="title">Titel &amp; Untertitel</string>
+    <string name="hello">"Hallo"</string>
+    <string name="bye">Tschüss</string>
+</resources>`,
+	})
+
+	catalog, err := ExportPO(dir, "de", Options{SkipOutdated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ImportTranslations(dir, "de", catalog.Entries, Options{SkipOutdated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Updated) > 0 || len(report.Files) > 0 {
+		t.Errorf("ImportTranslations() of the exported translations updated %v in %v, want none", report.Updated, report.Files)
+	}
+}
+
+// assertFileContent fails the test if the file at the given path can't be read or
+// its content isn't 'want'.
+func assertFileContent(t *testing.T, path, want string) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != want {
+		t.Errorf("content of %s = %s, want %s", path, content, want)
+	}
+}
@@ -0,0 +1,117 @@
+package translations
+
+import (
+	"fmt"
+	"time"
+)
+
+// Statuses of the translations of an inspected string.
+const (
+	TranslatedStatus  = "translated"   // the locale has an up-to-date translation of the string
+	MissingStatus     = "missing"      // the locale is expected to translate the string but doesn't
+	OutdatedStatus    = "outdated"     // the translation was last modified before the default string
+	NotExpectedStatus = "not-expected" // the locale isn't expected to translate the string
+)
+
+// LocaleStatus declares the output structure for the translation of a string in a
+// single locale.
+type LocaleStatus struct {
+	Locale         string     `json:"locale"`
+	Status         string     `json:"status"` // one of TranslatedStatus, MissingStatus, OutdatedStatus or NotExpectedStatus
+	Value          string     `json:"value,omitempty"`
+	File           string     `json:"file,omitempty"`
+	Line           int        `json:"line,omitempty"`
+	LastModified   *time.Time `json:"last_modified,omitempty"` // unknown for translations in archives and when SkipOutdated is set
+	LastModifiedBy string     `json:"last_modified_by,omitempty"`
+}
+
+// StringInspection declares the output structure for the translations of a single
+// default string in all locales.
+type StringInspection struct {
+	Name           string         `json:"name"`
+	Value          string         `json:"value"`
+	Type           string         `json:"type"`
+	File           string         `json:"file"`
+	Line           int            `json:"line"`
+	LastModified   *time.Time     `json:"last_modified,omitempty"`
+	LastModifiedBy string         `json:"last_modified_by,omitempty"`
+	Locales        []LocaleStatus `json:"locales"` // sorted by the locales, excluding the default locale
+	Warnings       []string       `json:"-"`
+}
+
+// InspectString finds the translations of the default string with the given name,
+// e.g. 'checkout_button' or 'planets[0]', in all locales of the Android project at
+// 'dir'. Unlike Scan, it reports every locale along with the value and the last
+// modification of its translation, to explain why the string is reported or not.
+func InspectString(dir, name string, opts Options) (StringInspection, error) {
+	s := &scanner{dir: dir, opts: opts}
+	localeStrings, _, _, err := s.findLocaleStrings()
+	if err != nil {
+		return StringInspection{}, err
+	}
+
+	sourceLocale := getSourceLocale(opts)
+	str, ok := localeStrings[sourceLocale][name]
+	if !ok {
+		return StringInspection{}, fmt.Errorf("unable to find translatable string %q in default locale %q", name, sourceLocale)
+	}
+
+	str.OnlyLocales = opts.expectedLocales(str)
+	inspection := StringInspection{
+		Name:           str.Name,
+		Value:          str.TrimmedValue(),
+		Type:           str.Type,
+		File:           s.relPath(str.File),
+		Line:           str.Line,
+		LastModified:   timeOrNil(str.LastModified),
+		LastModifiedBy: str.LastModifiedBy,
+		Locales:        make([]LocaleStatus, 0, len(localeStrings)),
+	}
+
+	overlays := map[string]bool{}
+	for _, locale := range opts.OverlayLocales {
+		overlays[canonicalLocale(locale)] = true
+	}
+
+	for _, locale := range localeStrings.sortedLocales() {
+		if locale == sourceLocale {
+			continue
+		}
+
+		status := LocaleStatus{Locale: locale, Status: TranslatedStatus}
+		localeStr, ok := localeStrings[locale][name]
+		if ok {
+			status.Value = localeStr.TrimmedValue()
+			status.File = s.relPath(localeStr.File)
+			status.Line = localeStr.Line
+			status.LastModified = timeOrNil(localeStr.LastModified)
+			status.LastModifiedBy = localeStr.LastModifiedBy
+		}
+
+		if !isTranslated(localeStrings[locale], str) {
+			status.Status = MissingStatus
+			if overlays[locale] || !isExpectedIn(str, locale) {
+				status.Status = NotExpectedStatus
+			}
+		} else if ok && isOutdated(localeStr, str) {
+			status.Status = OutdatedStatus
+			if opts.IgnoreReformatting && s.isValueUnchanged(str, localeStr.LastModified) {
+				status.Status = TranslatedStatus
+			}
+		}
+
+		inspection.Locales = append(inspection.Locales, status)
+	}
+
+	inspection.Warnings = s.warnings
+	return inspection, nil
+}
+
+// timeOrNil returns a pointer to the given time, or nil if it is zero.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
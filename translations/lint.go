@@ -0,0 +1,365 @@
+package translations
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	urlExpr           = regexp.MustCompile(`^(?i)((https?|ftp)://|www\.|mailto:)\S+$`)
+	versionExpr       = regexp.MustCompile(`^v?\d+(\.\d+)+([-+][0-9A-Za-z.-]+)?$`)
+	formatOnlyExpr    = regexp.MustCompile(`^([^\pL%]*%(\d+\$)?[-#+ 0,(]*\d*(\.\d+)?[a-zA-Z%])+[^\pL%]*$`)
+	letterlessExpr    = regexp.MustCompile(`^[^\pL]*[^\pL\pP\s][^\pL]*$`) // not only punctuation, e.g. '…', which is localized
+	nonTranslatableRe = []*regexp.Regexp{urlExpr, versionExpr, formatOnlyExpr, letterlessExpr}
+)
+
+// looksNonTranslatable uses simple heuristics to check if the given value looks
+// like it shouldn't be translated, e.g. URLs, version numbers, pure format
+// specifiers and values without any letters other than punctuation.
+func looksNonTranslatable(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+
+	for _, expr := range nonTranslatableRe {
+		if expr.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	resourceValueExpr = regexp.MustCompile(`^[@?]`) // resource and theme attribute references
+	colorExpr         = regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	dimensionExpr     = regexp.MustCompile(`^-?\d+(\.\d+)?(dp|dip|sp|px|pt|in|mm)$`)
+	booleanExpr       = regexp.MustCompile(`^(true|false)$`)
+	typedValueRe      = []*regexp.Regexp{resourceValueExpr, colorExpr, dimensionExpr, booleanExpr}
+)
+
+// containsText uses simple heuristics to check if any of the given items of a
+// typed '<array>' looks like human text rather than a typed value, e.g. a resource
+// reference, a color or a dimension.
+func containsText(items []string) bool {
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || looksNonTranslatable(item) {
+			continue
+		}
+
+		isTypedValue := false
+		for _, expr := range typedValueRe {
+			if expr.MatchString(item) {
+				isTypedValue = true
+				break
+			}
+		}
+
+		if !isTypedValue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findSuggestedNonTranslatable returns the given default strings that look like
+// they shouldn't be translated, sorted by their names.
+func findSuggestedNonTranslatable(defaultStrings map[string]xmlStringResource) []SuggestedString {
+	suggested := make([]SuggestedString, 0)
+	for _, str := range defaultStrings {
+		if looksNonTranslatable(str.Value) {
+			suggested = append(suggested, SuggestedString{
+				Name:  str.Name,
+				Value: str.TrimmedValue(),
+				File:  str.File,
+				Line:  str.Line,
+			})
+		}
+	}
+
+	sort.Slice(suggested, func(i, j int) bool { return suggested[i].Name < suggested[j].Name })
+	return suggested
+}
+
+// findNonTranslatable returns the strings, string arrays and plurals of the given
+// source locale that are marked with 'translatable="false"' or defined in the
+// 'donottranslate.xml' files next to its values files, sorted by their names and
+// files. The values files that can't be parsed are skipped, since Scan has already
+// failed or warned about them.
+func (s *scanner) findNonTranslatable(sourceLocale string) ([]ExcludedString, error) {
+	valuesFiles, err := s.findScannedValuesFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	nonTranslatable := make([]ExcludedString, 0)
+	add := func(file string, content []byte, resources *xmlStringResources, attrOnly bool) {
+		reason := DoNotTranslateFileReason
+		if attrOnly {
+			reason = TranslatableAttrReason
+		}
+
+		addRes := func(tag, name string, translatable xmlTranslatable) {
+			if attrOnly && translatable.IsTranslatable() {
+				return
+			}
+
+			line, _, _ := getLineRange(content, tag, name)
+			nonTranslatable = append(nonTranslatable, ExcludedString{Name: name, File: s.relPath(file), Line: line, Reason: reason})
+		}
+
+		for _, str := range resources.Strings {
+			addRes("string", str.Name, str.xmlTranslatable)
+		}
+
+		for _, strArr := range resources.StringArrays {
+			addRes("string-array", strArr.Name, strArr.xmlTranslatable)
+		}
+
+		for _, plurals := range resources.Plurals {
+			addRes("plurals", plurals.Name, plurals.xmlTranslatable)
+		}
+	}
+
+	seenDirs := map[string]bool{}
+	for _, file := range valuesFiles {
+		if getLocaleForValuesFile(file) != sourceLocale {
+			continue
+		}
+
+		if content, resources, _, err := parseValuesFile(file); err == nil {
+			add(file, content, resources, true)
+		}
+
+		dir := filepath.Dir(file)
+		if isArchiveEntry(file) || seenDirs[dir] {
+			continue
+		}
+
+		seenDirs[dir] = true
+		doNotTranslateFile := filepath.Join(dir, doNotTranslateFileName)
+		if _, err := os.Stat(doNotTranslateFile); err != nil {
+			continue
+		}
+
+		if content, resources, _, err := parseValuesFile(doNotTranslateFile); err == nil {
+			add(doNotTranslateFile, content, resources, false)
+		} else {
+			s.warnf("skipping invalid values file: %s", err)
+		}
+	}
+
+	sort.SliceStable(nonTranslatable, func(i, j int) bool {
+		if nonTranslatable[i].Name != nonTranslatable[j].Name {
+			return nonTranslatable[i].Name < nonTranslatable[j].Name
+		}
+
+		return nonTranslatable[i].File < nonTranslatable[j].File
+	})
+
+	return nonTranslatable, nil
+}
+
+// punctuationEquivalents maps the trailing punctuation marks to their equivalents
+// in other scripts, e.g. the ideographic full stop in Chinese and Japanese.
+var punctuationEquivalents = map[rune][]rune{
+	'.': {'.', '。', '।', '۔'},
+	':': {':', '：'},
+	'!': {'!', '！', '¡'},
+	'?': {'?', '？', '؟', '¿'},
+}
+
+// findPunctuationWarnings returns the formatting drift between the given default
+// strings and their translations in locales other than 'sourceLocale', sorted by
+// the string names and locales. See checkPunctuation.
+func findPunctuationWarnings(defaultStrings map[string]xmlStringResource, localeStrings localeStringsMap, sourceLocale string) []PunctuationWarning {
+	warnings := make([]PunctuationWarning, 0)
+	for _, locale := range localeStrings.sortedLocales() {
+		if locale == sourceLocale {
+			continue
+		}
+
+		for name, str := range defaultStrings {
+			localeStr, ok := localeStrings[locale][name]
+			if !ok {
+				continue
+			}
+
+			for _, message := range checkPunctuation(str.TrimmedValue(), localeStr.TrimmedValue()) {
+				warnings = append(warnings, PunctuationWarning{Name: name, Locale: locale, Message: message})
+			}
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Name != warnings[j].Name {
+			return warnings[i].Name < warnings[j].Name
+		}
+
+		return warnings[i].Locale < warnings[j].Locale
+	})
+
+	return warnings
+}
+
+// findLengthWarnings returns the translations in locales other than 'sourceLocale'
+// that are more than 'maxRatio' times as long as their default strings, sorted by
+// the string names and locales. The lengths are counted in characters. Translations
+// shorter than 'minLength' characters are never reported, since short strings,
+// e.g. 'OK', are often much longer in other languages without breaking layouts.
+func findLengthWarnings(defaultStrings map[string]xmlStringResource, localeStrings localeStringsMap, sourceLocale string, maxRatio float64, minLength int) []LengthWarning {
+	warnings := make([]LengthWarning, 0)
+	for _, locale := range localeStrings.sortedLocales() {
+		if locale == sourceLocale {
+			continue
+		}
+
+		for name, str := range defaultStrings {
+			localeStr, ok := localeStrings[locale][name]
+			if !ok {
+				continue
+			}
+
+			defaultLength := utf8.RuneCountInString(str.TrimmedValue())
+			length := utf8.RuneCountInString(localeStr.TrimmedValue())
+			if defaultLength == 0 || length < minLength {
+				continue
+			}
+
+			if ratio := float64(length) / float64(defaultLength); ratio > maxRatio {
+				warnings = append(warnings, LengthWarning{
+					Name:          name,
+					Locale:        locale,
+					Length:        length,
+					DefaultLength: defaultLength,
+					Ratio:         math.Round(ratio*100) / 100,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Name != warnings[j].Name {
+			return warnings[i].Name < warnings[j].Name
+		}
+
+		return warnings[i].Locale < warnings[j].Locale
+	})
+
+	return warnings
+}
+
+// checkPunctuation returns the messages for each formatting drift of 'translation'
+// from 'value'.
+func checkPunctuation(value, translation string) []string {
+	messages := make([]string, 0)
+	for _, check := range []func(string, string) string{
+		checkTrailingPunctuation,
+		checkEllipsis,
+		checkSurroundingWhitespace,
+		checkCapitalization,
+	} {
+		if message := check(value, translation); message != "" {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages
+}
+
+// checkTrailingPunctuation checks if 'value' ends with a period, colon, exclamation
+// or question mark and 'translation' doesn't end with it or one of its equivalents.
+func checkTrailingPunctuation(value, translation string) string {
+	value, translation = unquote(value), unquote(translation)
+	if value == "" || translation == "" {
+		return ""
+	}
+
+	last, _ := utf8.DecodeLastRuneInString(strings.TrimRightFunc(value, unicode.IsSpace))
+	equivalents, ok := punctuationEquivalents[last]
+	if !ok || strings.HasSuffix(value, "...") {
+		return "" // ellipsis is checked by checkEllipsis
+	}
+
+	translationLast, _ := utf8.DecodeLastRuneInString(strings.TrimRightFunc(translation, unicode.IsSpace))
+	for _, r := range equivalents {
+		if r == translationLast {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("missing trailing %q", last)
+}
+
+// checkEllipsis checks if 'value' contains an ellipsis, i.e. '…' or '...', that is
+// absent in 'translation'.
+func checkEllipsis(value, translation string) string {
+	hasEllipsis := func(s string) bool { return strings.Contains(s, "…") || strings.Contains(s, "...") }
+	if hasEllipsis(value) && !hasEllipsis(translation) {
+		return "missing ellipsis"
+	}
+
+	return ""
+}
+
+// checkSurroundingWhitespace checks if 'value' and 'translation' differ in having
+// leading or trailing whitespace. Since whitespace is only significant when it is
+// preserved, e.g. inside double quotes, the values are compared after unquoting.
+func checkSurroundingWhitespace(value, translation string) string {
+	value, translation = unquote(value), unquote(translation)
+	if value == "" || translation == "" {
+		return ""
+	}
+
+	leading := func(s string) bool { r, _ := utf8.DecodeRuneInString(s); return unicode.IsSpace(r) }
+	trailing := func(s string) bool { r, _ := utf8.DecodeLastRuneInString(s); return unicode.IsSpace(r) }
+	if leading(value) != leading(translation) {
+		return "mismatched leading whitespace"
+	}
+
+	if trailing(value) != trailing(translation) {
+		return "mismatched trailing whitespace"
+	}
+
+	return ""
+}
+
+// checkCapitalization checks if the first letter of 'value' is upper case while the
+// first letter of 'translation' is lower case. Scripts without letter case, e.g.
+// Chinese, are never reported.
+func checkCapitalization(value, translation string) string {
+	first := func(s string) rune {
+		for _, r := range unquote(s) {
+			if unicode.IsLetter(r) {
+				return r
+			}
+		}
+
+		return 0
+	}
+
+	if unicode.IsUpper(first(value)) && unicode.IsLower(first(translation)) {
+		return "first letter isn't capitalized"
+	}
+
+	return ""
+}
+
+// unquote removes the double quotes around the given value, if any.
+func unquote(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
@@ -0,0 +1,179 @@
+package translations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLooksNonTranslatable(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"https://example.com/privacy", true},
+		{"www.example.com", true},
+		{"mailto:support@example.com", true},
+		{"1.5", true},
+		{"v2.0.1", true},
+		{"1.0.0-beta.1", true},
+		{"%1$s", true},
+		{"%s: %s", true},
+		{"%d%%", true},
+		{"42", true},
+		{"+1 (555) 123-4567", true},
+		{"→", true},
+		{"…", false},
+		{"—", false},
+		{"?!", false},
+		{"", false},
+		{"Hello", false},
+		{"%d messages", false},
+		{"Version %s", false},
+		{"Visit https://example.com", false},
+		{"1.5 km", false},
+	}
+
+	for _, test := range tests {
+		if got := looksNonTranslatable(test.value); got != test.want {
+			t.Errorf("looksNonTranslatable(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestCheckPunctuation(t *testing.T) {
+	tests := []struct {
+		value       string
+		translation string
+		want        []string
+	}{
+		{"Hello.", "Hallo.", []string{}},
+		{"Loading...", "laden", []string{"missing ellipsis", "first letter isn't capitalized"}},
+		{`" Next"`, `"weiter"`, []string{"mismatched leading whitespace", "first letter isn't capitalized"}},
+		{"Done!", "完成", []string{`missing trailing '!'`}},
+	}
+
+	for _, test := range tests {
+		if got := checkPunctuation(test.value, test.translation); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("checkPunctuation(%q, %q) = %q, want %q", test.value, test.translation, got, test.want)
+		}
+	}
+}
+
+func TestCheckTrailingPunctuation(t *testing.T) {
+	tests := []struct {
+		value       string
+		translation string
+		want        string
+	}{
+		{"Saved.", "Gespeichert.", ""},
+		{"Saved.", "Gespeichert", `missing trailing '.'`},
+		{"Saved. ", "Gespeichert. ", ""},
+		{"Name:", "Nom :", ""},
+		{"Name:", "名前：", ""},
+		{"Saved.", "已保存。", ""},
+		{"Saved.", "सहेजा गया।", ""},
+		{"Saved.", "محفوظ ہو گیا۔", ""},
+		{"Delete?", "حذف؟", ""},
+		{"Delete?", "מחק?", ""},
+		{"Delete?", "حذف", `missing trailing '?'`},
+		{"Welcome!", "ようこそ！", ""},
+		{"Loading...", "Laden", ""},
+		{`"Saved."`, `"Gespeichert"`, `missing trailing '.'`},
+		{"Saved", "Gespeichert.", ""},
+		{"Saved.", "", ""},
+	}
+
+	for _, test := range tests {
+		if got := checkTrailingPunctuation(test.value, test.translation); got != test.want {
+			t.Errorf("checkTrailingPunctuation(%q, %q) = %q, want %q", test.value, test.translation, got, test.want)
+		}
+	}
+}
+
+func TestCheckEllipsis(t *testing.T) {
+	tests := []struct {
+		value       string
+		translation string
+		want        string
+	}{
+		{"Loading…", "Laden…", ""},
+		{"Loading...", "Laden…", ""},
+		{"Loading…", "Laden...", ""},
+		{"Loading…", "加载中……", ""},
+		{"Loading…", "Laden", "missing ellipsis"},
+		{"Loading", "Laden…", ""},
+	}
+
+	for _, test := range tests {
+		if got := checkEllipsis(test.value, test.translation); got != test.want {
+			t.Errorf("checkEllipsis(%q, %q) = %q, want %q", test.value, test.translation, got, test.want)
+		}
+	}
+}
+
+func TestCheckSurroundingWhitespace(t *testing.T) {
+	tests := []struct {
+		value       string
+		translation string
+		want        string
+	}{
+		{"Hello", "Hallo", ""},
+		{`" Hello"`, `" Hallo"`, ""},
+		{`" Hello"`, `"Hallo"`, "mismatched leading whitespace"},
+		{`"Hello "`, `"Hallo"`, "mismatched trailing whitespace"},
+		{`"Hello"`, `"Hallo "`, "mismatched trailing whitespace"},
+		{`"Hello "`, `"مرحبا "`, ""},
+		{`"Hello "`, "", ""},
+	}
+
+	for _, test := range tests {
+		if got := checkSurroundingWhitespace(test.value, test.translation); got != test.want {
+			t.Errorf("checkSurroundingWhitespace(%q, %q) = %q, want %q", test.value, test.translation, got, test.want)
+		}
+	}
+}
+
+func TestCheckCapitalization(t *testing.T) {
+	tests := []struct {
+		value       string
+		translation string
+		want        string
+	}{
+		{"Hello", "Hallo", ""},
+		{"Hello", "hallo", "first letter isn't capitalized"},
+		{`"%1$s items"`, `"%1$s Elemente"`, ""},
+		{"hello", "hallo", ""},
+		{"Hello", "你好", ""},
+		{"Hello", "مرحبا", ""},
+		{"Hello", "שלום", ""},
+		{"¿Hello?", "¿hola?", "first letter isn't capitalized"},
+		{"42", "42", ""},
+	}
+
+	for _, test := range tests {
+		if got := checkCapitalization(test.value, test.translation); got != test.want {
+			t.Errorf("checkCapitalization(%q, %q) = %q, want %q", test.value, test.translation, got, test.want)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`"Hello"`, "Hello"},
+		{`" Hello "`, " Hello "},
+		{`""`, ""},
+		{`"`, `"`},
+		{`"Hello`, `"Hello`},
+		{`Hello "world"`, `Hello "world"`},
+		{"Hello", "Hello"},
+	}
+
+	for _, test := range tests {
+		if got := unquote(test.value); got != test.want {
+			t.Errorf("unquote(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package translations
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// mccMncExpr matches the mobile country and network code qualifiers, e.g. 'mcc310'
+// and 'mnc004', that precede the locale qualifier in resource directory names.
+var mccMncExpr = regexp.MustCompile(`^(mcc|mnc)\d+$`)
+
+// configQualifierExpr matches the resource qualifiers other than MCC, MNC and locale
+// that may follow the locale qualifier in resource directory names, e.g. 'night',
+// 'land', 'sw600dp' and 'v21'.
+var configQualifierExpr = regexp.MustCompile(`^(ldrtl|ldltr|sw\d+dp|[wh]\d+dp|small|normal|large|xlarge|long|notlong|` +
+	`round|notround|widecg|nowidecg|highdr|lowdr|port|land|square|car|desk|television|appliance|watch|vrheadset|` +
+	`night|notnight|[lmt]dpi|x{0,3}hdpi|nodpi|anydpi|\d+dpi|notouch|finger|stylus|keysexposed|keyshidden|keyssoft|` +
+	`nokeys|qwerty|12key|navexposed|navhidden|nonav|dpad|trackball|wheel|v\d+)$`)
+
+// regionQualifierExpr matches the legacy Android region qualifiers, e.g. 'rUS' and
+// 'r419', that follow the language qualifier.
+var regionQualifierExpr = regexp.MustCompile(`^r([A-Z]{2}|[0-9]{3})$`)
+
+// getLocaleForValuesFile returns the locale for the given values file. It is the
+// locale qualifier of its directory as returned by getLocaleQualifier, except for
+// BCP 47 qualifiers which are converted to canonical BCP 47 tags, e.g. 'sr-Latn'
+// for 'values-b+sr+Latn'.
+func getLocaleForValuesFile(path string) string {
+	return canonicalLocale(getLocaleQualifier(path))
+}
+
+// getLocaleQualifier returns the locale qualifier of the given values file, e.g.
+// 'en-rUS' for 'values-mcc310-mnc004-en-rUS-night'. See splitQualifiers. If it has
+// no locale qualifier, e.g. 'values' and 'values-night', it returns the
+// DefaultLocale constant.
+func getLocaleQualifier(path string) string {
+	locale, _ := splitQualifiers(path)
+	if locale == "" {
+		return DefaultLocale
+	}
+
+	return locale
+}
+
+// hasConfigQualifiers checks if the directory of the given values file has resource
+// qualifiers other than MCC, MNC and locale, e.g. 'values-night' and 'values-de-v21'.
+func hasConfigQualifiers(path string) bool {
+	_, config := splitQualifiers(path)
+	return len(config) > 0
+}
+
+// splitQualifiers splits the suffix after 'values-' of the given values file's
+// directory into its locale qualifier and the configuration qualifiers following
+// it, leaving out the leading MCC and MNC qualifiers. If the suffix doesn't follow
+// the Android qualifier order, e.g. 'values-english', all of it is returned as the
+// locale qualifier so that it is reported as an invalid locale.
+func splitQualifiers(path string) (string, []string) {
+	split := strings.Split(filepath.Base(filepath.Dir(path)), "-")
+	i := 1
+	for i < len(split) && mccMncExpr.MatchString(split[i]) {
+		i++
+	}
+
+	start := i
+	if i < len(split) && isLanguageQualifier(split[i]) {
+		i++
+		if i < len(split) && regionQualifierExpr.MatchString(split[i]) {
+			i++
+		}
+	}
+
+	for _, q := range split[i:] {
+		if !configQualifierExpr.MatchString(q) {
+			return strings.Join(split[start:], "-"), nil
+		}
+	}
+
+	return strings.Join(split[start:i], "-"), split[i:]
+}
+
+// isLanguageQualifier checks if the given resource qualifier is a language, i.e. a
+// BCP 47 qualifier, e.g. 'b+sr+Latn', or a known ISO 639 language code, e.g. 'fr',
+// as opposed to other resource qualifiers such as 'night' and 'v21'.
+func isLanguageQualifier(q string) bool {
+	if strings.HasPrefix(q, "b+") {
+		return true
+	}
+
+	if configQualifierExpr.MatchString(q) || !legacyLanguageExpr.MatchString(q) {
+		return false
+	}
+
+	_, err := language.ParseBase(q)
+	return err == nil
+}
+
+// canonicalLocale converts the given BCP 47 locale qualifier, e.g. 'b+sr+Latn', to
+// its canonical BCP 47 tag, e.g. 'sr-Latn'. Other locale qualifiers, and the BCP 47
+// qualifiers that can't be parsed, are returned as is.
+func canonicalLocale(qualifier string) string {
+	if !strings.HasPrefix(qualifier, "b+") {
+		return qualifier
+	}
+
+	tag, err := language.Parse(strings.ReplaceAll(strings.TrimPrefix(qualifier, "b+"), "+", "-"))
+	if err != nil {
+		return qualifier
+	}
+
+	return tag.String()
+}
+
+// LocaleDisplayName returns the English display name for the given locale
+// qualifier, e.g. 'Chinese (China)' for 'zh-rCN' and 'Serbian (Latin)' for
+// 'b+sr+Latn'. The name is made of the names of the language and of the script and
+// region, if the locale has them, since the names of the whole tags can be wrong,
+// e.g. 'Serbo-Croatian' for 'sr-Latn'. It returns the locale itself if no display
+// name is available.
+func LocaleDisplayName(locale string) string {
+	var tag string
+	if strings.HasPrefix(locale, "b+") { // BCP 47 qualifier, e.g. 'b+sr+Latn'
+		tag = strings.ReplaceAll(strings.TrimPrefix(locale, "b+"), "+", "-")
+	} else {
+		parts := strings.Split(locale, "-")
+		for i := range parts {
+			if i > 0 && (len(parts[i]) == 3 || len(parts[i]) == 4) && strings.HasPrefix(parts[i], "r") {
+				parts[i] = strings.TrimPrefix(parts[i], "r")
+			}
+		}
+
+		tag = strings.Join(parts, "-")
+	}
+
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return locale
+	}
+
+	base, _ := parsed.Base()
+	name := display.English.Languages().Name(base)
+	if name == "" {
+		return locale
+	}
+
+	details := make([]string, 0, 2)
+	if script, confidence := parsed.Script(); confidence == language.Exact {
+		details = append(details, display.English.Scripts().Name(script))
+	}
+
+	if region, confidence := parsed.Region(); confidence == language.Exact {
+		details = append(details, display.English.Regions().Name(region))
+	}
+
+	if len(details) > 0 {
+		name = fmt.Sprintf("%s (%s)", name, strings.Join(details, ", "))
+	}
+
+	return name
+}
+
+// compareSupportedLocales returns the sorted locales with strings that aren't in
+// 'supported', and the sorted supported locales without any strings. The source
+// locale and the default locale are always expected, so they are never unexpected.
+func compareSupportedLocales(localeStrings localeStringsMap, sourceLocale string, supported []string) ([]string, []string) {
+	expected := map[string]bool{sourceLocale: true, DefaultLocale: true}
+	missing := make([]string, 0)
+	for _, locale := range supported {
+		locale = canonicalLocale(locale)
+		if _, ok := localeStrings[locale]; !ok && !expected[locale] {
+			missing = append(missing, locale)
+		}
+
+		expected[locale] = true
+	}
+
+	unexpected := make([]string, 0)
+	for _, locale := range localeStrings.sortedLocales() {
+		if !expected[locale] {
+			unexpected = append(unexpected, locale)
+		}
+	}
+
+	sort.Strings(missing)
+	return unexpected, missing
+}
+
+// localeDisplayName is like LocaleDisplayName, but names the default locale after
+// 'defaultLanguage', i.e. the 'tools:locale' of the default values files, if it is
+// declared.
+func localeDisplayName(locale, defaultLanguage string) string {
+	if locale == DefaultLocale && defaultLanguage != "" {
+		return LocaleDisplayName(defaultLanguage)
+	}
+
+	return LocaleDisplayName(locale)
+}
+
+// localeDisplayNames is like LocaleDisplayNames, but names the default locale after
+// 'defaultLanguage' as per localeDisplayName.
+func localeDisplayNames(locales []string, defaultLanguage string) []string {
+	names := make([]string, 0, len(locales))
+	for _, locale := range locales {
+		names = append(names, localeDisplayName(locale, defaultLanguage))
+	}
+
+	return names
+}
+
+// localeLanguage returns the lowercase language of the given locale qualifier,
+// e.g. 'pt' for 'pt-rBR' and 'sr' for 'b+sr+Latn'.
+func localeLanguage(locale string) string {
+	language := strings.TrimPrefix(locale, "b+")
+	if i := strings.IndexAny(language, "-+_"); i >= 0 {
+		language = language[:i]
+	}
+
+	return strings.ToLower(language)
+}
+
+// LocaleDisplayNames returns display names for the given locales in the same
+// order. See LocaleDisplayName.
+func LocaleDisplayNames(locales []string) []string {
+	names := make([]string, 0, len(locales))
+	for _, locale := range locales {
+		names = append(names, LocaleDisplayName(locale))
+	}
+
+	return names
+}
+
+// legacyLocaleExpr matches the legacy Android locale qualifiers, e.g. 'fr', 'fr-rCA'
+// and 'es-r419'.
+var legacyLocaleExpr = regexp.MustCompile(`^([a-z]{2,3})(-r([A-Z]{2}|[0-9]{3}))?$`)
+
+// legacyLanguageExpr matches the language part of the legacy Android locale
+// qualifiers.
+var legacyLanguageExpr = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// IsValidLocale checks if the given locale qualifier is either a legacy Android
+// qualifier with a known ISO 639 language and an optional '-r' prefixed region, or
+// a well-formed BCP 47 qualifier, e.g. 'b+sr+Latn'. The default locale is always
+// valid.
+func IsValidLocale(code string) bool {
+	if code == DefaultLocale {
+		return true
+	}
+
+	if strings.HasPrefix(code, "b+") {
+		_, err := language.Parse(strings.ReplaceAll(strings.TrimPrefix(code, "b+"), "+", "-"))
+		return err == nil
+	}
+
+	match := legacyLocaleExpr.FindStringSubmatch(code)
+	if match == nil {
+		return false
+	}
+
+	if _, err := language.ParseBase(match[1]); err != nil {
+		return false
+	}
+
+	if match[3] != "" {
+		if _, err := language.ParseRegion(match[3]); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterInvalidLocales returns the given values files whose locale qualifiers are
+// valid as per IsValidLocale. It also returns the sorted list of invalid locales.
+func filterInvalidLocales(files []string) ([]string, []string) {
+	filtered := make([]string, 0, len(files))
+	invalid := map[string]bool{}
+	for _, file := range files {
+		locale := getLocaleQualifier(file)
+		if IsValidLocale(locale) {
+			filtered = append(filtered, file)
+		} else {
+			invalid[locale] = true
+		}
+	}
+
+	invalidLocales := make([]string, 0, len(invalid))
+	for locale := range invalid {
+		invalidLocales = append(invalidLocales, locale)
+	}
+
+	sort.Strings(invalidLocales)
+	return filtered, invalidLocales
+}
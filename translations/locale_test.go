@@ -0,0 +1,145 @@
+package translations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsValidLocale(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{DefaultLocale, true},
+		{"fr", true},
+		{"fr-rCA", true},
+		{"es-r419", true},
+		{"b+sr+Latn", true},
+		{"engg", false},
+		{"fr_CA", false},
+		{"fr-CA", false},
+		{"fr-rZZZ", false},
+		{"b+", false},
+	}
+
+	for _, test := range tests {
+		if got := IsValidLocale(test.code); got != test.want {
+			t.Errorf("IsValidLocale(%q) = %v, want %v", test.code, got, test.want)
+		}
+	}
+}
+
+func TestFilterInvalidLocales(t *testing.T) {
+	files := []string{
+		"res/values/strings.xml",
+		"res/values-night/strings.xml",
+		"res/values-v21/strings.xml",
+		"res/values-fr-rCA/strings.xml",
+		"res/values-english/strings.xml",
+		"res/values-fr_CA/strings.xml",
+	}
+
+	filtered, invalid := filterInvalidLocales(files)
+	if want := files[:4]; !reflect.DeepEqual(filtered, want) {
+		t.Errorf("filterInvalidLocales() files = %v, want %v", filtered, want)
+	}
+
+	if want := []string{"english", "fr_CA"}; !reflect.DeepEqual(invalid, want) {
+		t.Errorf("filterInvalidLocales() invalid = %v, want %v", invalid, want)
+	}
+}
+
+func TestGetLocaleForValuesFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"res/values/strings.xml", DefaultLocale},
+		{"res/values-fr-rCA/strings.xml", "fr-rCA"},
+		{"res/values-b+sr/strings.xml", "sr"},
+		{"res/values-b+sr+Latn/strings.xml", "sr-Latn"},
+		{"res/values-b+es+419/strings.xml", "es-419"},
+		{"res/values-b+zh+Hans+CN/strings.xml", "zh-Hans-CN"},
+		{"res/values-b+EN+us/strings.xml", "en-US"},
+		{"res/values-b+sr+Latn-night/strings.xml", "sr-Latn"},
+		{"res/values-mcc310-b+sr+Latn/strings.xml", "sr-Latn"},
+	}
+
+	for _, test := range tests {
+		if got := getLocaleForValuesFile(test.path); got != test.want {
+			t.Errorf("getLocaleForValuesFile(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestIsLanguageQualifier(t *testing.T) {
+	tests := []struct {
+		qualifier string
+		want      bool
+	}{
+		{"fr", true},
+		{"es", true},
+		{"b+sr+Latn", true},
+		{"night", false},
+		{"v21", false},
+		{"land", false},
+		{"mcc310", false},
+		{"sw600dp", false},
+		{"english", false},
+	}
+
+	for _, test := range tests {
+		if got := isLanguageQualifier(test.qualifier); got != test.want {
+			t.Errorf("isLanguageQualifier(%q) = %v, want %v", test.qualifier, got, test.want)
+		}
+	}
+}
+
+func TestHasConfigQualifiers(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"res/values/strings.xml", false},
+		{"res/values-night/strings.xml", true},
+		{"res/values-v21/strings.xml", true},
+		{"res/values-land/strings.xml", true},
+		{"res/values-mcc310/strings.xml", false},
+		{"res/values-sw600dp/strings.xml", true},
+		{"res/values-fr-rCA/strings.xml", false},
+		{"res/values-fr-rCA-night/strings.xml", true},
+		{"res/values-b+sr+Latn/strings.xml", false},
+		{"res/values-es-r419/strings.xml", false},
+		{"res/values-english/strings.xml", false},
+	}
+
+	for _, test := range tests {
+		if got := hasConfigQualifiers(test.path); got != test.want {
+			t.Errorf("hasConfigQualifiers(%q) = %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestLocaleDisplayName(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"fr", "French"},
+		{"fr-rCA", "French (Canada)"},
+		{"zh-rCN", "Chinese (China)"},
+		{"es-r419", "Spanish (Latin America)"},
+		{"b+sr+Latn", "Serbian (Latin)"},
+		{"sr-Latn", "Serbian (Latin)"},
+		{"b+zh+Hans+CN", "Chinese (Simplified Han, China)"},
+		{"zh-Hans-CN", "Chinese (Simplified Han, China)"},
+		{"es-419", "Spanish (Latin America)"},
+		{"engg", "engg"},
+	}
+
+	for _, test := range tests {
+		if got := LocaleDisplayName(test.locale); got != test.want {
+			t.Errorf("LocaleDisplayName(%q) = %q, want %q", test.locale, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package translations
+
+// ReportModel declares the complete data model of a report for custom rendering,
+// e.g. with templating engines other than the built-in output formats.
+type ReportModel struct {
+	Summary                  SummaryModel         `json:"summary"`
+	Locales                  []LocaleModel        `json:"locales"` // sorted by the locales
+	Strings                  []StringResource     `json:"strings"`
+	Duplicates               []DuplicateString    `json:"duplicates"`
+	SuggestedNonTranslatable []SuggestedString    `json:"suggested_non_translatable"`
+	NonTranslatable          []ExcludedString     `json:"non_translatable"`
+	OutdatedDiffs            []OutdatedDiff       `json:"outdated_diffs"`
+	PunctuationWarnings      []PunctuationWarning `json:"punctuation_warnings"`
+	LengthWarnings           []LengthWarning      `json:"length_warnings"`
+	EscapeWarnings           []EscapeWarning      `json:"escape_warnings"`
+	XliffMismatches          []XliffMismatch      `json:"xliff_mismatches"`
+	Divergences              []Divergence         `json:"divergences"`
+	InvalidLocales           []string             `json:"invalid_locales"`
+	UnexpectedLocales        []string             `json:"unexpected_locales"`
+	TotallyMissingLocales    []string             `json:"totally_missing_locales"`
+	EmptyLocales             []string             `json:"empty_locales"`
+	FixedGaps                []Gap                `json:"fixed_gaps"`
+	Warnings                 []string             `json:"warnings"`
+}
+
+// SummaryModel declares the totals of a report in ReportModel.
+type SummaryModel struct {
+	Coverage        float64 `json:"coverage"`
+	MissingCount    int     `json:"missing_count"`
+	OutdatedCount   int     `json:"outdated_count"`
+	AffectedCount   int     `json:"total_affected"`
+	DefaultLanguage string  `json:"default_language,omitempty"` // 'tools:locale' of the default values files
+}
+
+// LocaleModel declares the breakdown of a report for a single locale in
+// ReportModel.
+type LocaleModel struct {
+	Locale        string               `json:"locale"`
+	DisplayName   string               `json:"display_name"`
+	Coverage      float64              `json:"coverage"`
+	MissingCount  int                  `json:"missing_count"`  // number of strings missing in the locale
+	OutdatedCount int                  `json:"outdated_count"` // number of strings potentially outdated in the locale
+	TypeCounts    map[string]TypeCount `json:"type_counts"`    // resource type => counts
+}
+
+// Model returns the complete data model of the report. Unlike the report itself,
+// the lists of the model are never nil, so that they are rendered as empty JSON
+// arrays.
+func (r Report) Model() ReportModel {
+	model := ReportModel{
+		Summary: SummaryModel{
+			Coverage:        r.Coverage,
+			MissingCount:    r.MissingCount,
+			OutdatedCount:   r.OutdatedCount,
+			AffectedCount:   r.AffectedCount,
+			DefaultLanguage: r.DefaultLanguage,
+		},
+		Locales:                  make([]LocaleModel, 0, len(r.Locales)),
+		Strings:                  r.Strings,
+		Duplicates:               r.Duplicates,
+		SuggestedNonTranslatable: r.SuggestedNonTranslatable,
+		NonTranslatable:          r.NonTranslatable,
+		OutdatedDiffs:            r.OutdatedDiffs,
+		PunctuationWarnings:      r.PunctuationWarnings,
+		LengthWarnings:           r.LengthWarnings,
+		EscapeWarnings:           r.EscapeWarnings,
+		XliffMismatches:          r.XliffMismatches,
+		Divergences:              r.Divergences,
+		InvalidLocales:           r.InvalidLocales,
+		UnexpectedLocales:        r.UnexpectedLocales,
+		TotallyMissingLocales:    r.TotallyMissingLocales,
+		EmptyLocales:             r.EmptyLocales,
+		FixedGaps:                r.FixedGaps,
+		Warnings:                 r.Warnings,
+	}
+
+	missing, outdated := map[string]int{}, map[string]int{}
+	for _, res := range r.Strings {
+		for _, locale := range res.MissingLocales {
+			missing[locale]++
+		}
+
+		for _, locale := range res.OutdatedLocales {
+			outdated[locale]++
+		}
+	}
+
+	for _, locale := range r.Locales {
+		model.Locales = append(model.Locales, LocaleModel{
+			Locale:        locale,
+			DisplayName:   localeDisplayName(locale, r.DefaultLanguage),
+			Coverage:      r.LocaleCoverage[locale],
+			MissingCount:  missing[locale],
+			OutdatedCount: outdated[locale],
+			TypeCounts:    r.TypeCounts[locale],
+		})
+	}
+
+	if model.Strings == nil {
+		model.Strings = []StringResource{}
+	}
+
+	if model.Duplicates == nil {
+		model.Duplicates = []DuplicateString{}
+	}
+
+	if model.SuggestedNonTranslatable == nil {
+		model.SuggestedNonTranslatable = []SuggestedString{}
+	}
+
+	if model.NonTranslatable == nil {
+		model.NonTranslatable = []ExcludedString{}
+	}
+
+	if model.OutdatedDiffs == nil {
+		model.OutdatedDiffs = []OutdatedDiff{}
+	}
+
+	if model.PunctuationWarnings == nil {
+		model.PunctuationWarnings = []PunctuationWarning{}
+	}
+
+	if model.LengthWarnings == nil {
+		model.LengthWarnings = []LengthWarning{}
+	}
+
+	if model.EscapeWarnings == nil {
+		model.EscapeWarnings = []EscapeWarning{}
+	}
+
+	if model.XliffMismatches == nil {
+		model.XliffMismatches = []XliffMismatch{}
+	}
+
+	if model.Divergences == nil {
+		model.Divergences = []Divergence{}
+	}
+
+	if model.InvalidLocales == nil {
+		model.InvalidLocales = []string{}
+	}
+
+	if model.UnexpectedLocales == nil {
+		model.UnexpectedLocales = []string{}
+	}
+
+	if model.TotallyMissingLocales == nil {
+		model.TotallyMissingLocales = []string{}
+	}
+
+	if model.EmptyLocales == nil {
+		model.EmptyLocales = []string{}
+	}
+
+	if model.FixedGaps == nil {
+		model.FixedGaps = []Gap{}
+	}
+
+	if model.Warnings == nil {
+		model.Warnings = []string{}
+	}
+
+	return model
+}
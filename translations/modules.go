@@ -0,0 +1,88 @@
+package translations
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ModuleReport declares the report of a single Gradle module found by ScanModules.
+type ModuleReport struct {
+	Module string // path of the module relative to the scanned directory, '.' for the directory itself
+	Report Report
+}
+
+// ScanModules finds the Gradle modules in the Android project at 'dir', i.e. the
+// directories with 'src/*/res' resource roots, and scans each of them on its own,
+// so that the strings of a module are only compared to the default strings of the
+// same module. Nested modules aren't part of their parent modules. The modules
+// without any translations are left out. The returned reports are sorted by the
+// module paths.
+func ScanModules(dir string, opts Options) ([]ModuleReport, error) {
+	s := &scanner{dir: dir, opts: opts}
+	valuesFiles, err := s.findRootValuesFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	moduleFiles := map[string][]string{}
+	for _, file := range valuesFiles {
+		if isArchiveEntry(file) {
+			continue // archives are scanned with the module containing them
+		}
+
+		if module, ok := getModule(s.relPath(file)); ok {
+			moduleFiles[module] = append(moduleFiles[module], file)
+		}
+	}
+
+	modules := make([]string, 0, len(moduleFiles))
+	for module := range moduleFiles {
+		modules = append(modules, module)
+	}
+
+	sort.Strings(modules)
+	reports := make([]ModuleReport, 0, len(modules))
+	for _, module := range modules {
+		moduleDir := filepath.Join(dir, module)
+		moduleOpts := opts
+		moduleOpts.Files = make([]string, 0, len(moduleFiles[module]))
+		for _, file := range moduleFiles[module] {
+			if rel, err := filepath.Rel(moduleDir, file); err == nil {
+				moduleOpts.Files = append(moduleOpts.Files, rel)
+			}
+		}
+
+		report, err := Scan(moduleDir, moduleOpts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to scan module %s", module)
+		}
+
+		if len(report.Locales) > 0 {
+			reports = append(reports, ModuleReport{Module: module, Report: report})
+		}
+	}
+
+	return reports, nil
+}
+
+// getModule returns the path of the module that the given slash-separated path
+// belongs to, i.e. the path segments preceding the last 'src' segment, e.g. 'app'
+// for 'app/src/main/res/values/strings.xml'. It returns false if the path isn't in
+// a source set.
+func getModule(path string) (string, bool) {
+	segments := strings.Split(path, "/")
+	for i := len(segments) - 2; i >= 0; i-- {
+		if segments[i] == "src" {
+			if i == 0 {
+				return ".", true
+			}
+
+			return strings.Join(segments[:i], "/"), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,266 @@
+package translations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// pluralQuantities lists the quantities of '<plurals>' items in the CLDR order of
+// the plural forms.
+var pluralQuantities = []string{"zero", "one", "two", "few", "many", "other"}
+
+// POEntry declares the output structure for a single message of a gettext PO file.
+type POEntry struct {
+	Context  string   // name of the string or the array item, or the plurals for plural messages
+	ID       string   // default value, i.e. of the 'one' quantity for plural messages if it exists
+	IDPlural string   // default value of the 'other' quantity, only set for plural messages
+	Str      []string // translations, one per quantity of the locale for plural messages, empty if missing
+	Comment  string   // XML comment directly above the default string, if any
+	File     string
+	Line     int
+	Fuzzy    bool // if true, the translation is potentially outdated
+}
+
+// POCatalog declares the output structure for the default strings of an Android
+// project and their translations in a single locale as gettext PO messages.
+type POCatalog struct {
+	Locale   string // locale of the translations
+	Language string // gettext language code of the locale, e.g. 'pt_BR' for 'pt-rBR'
+
+	// gettext 'Plural-Forms' header of the locale, e.g. 'nplurals=2; plural=(n != 1);'
+	// for 'de', empty if its CLDR plural rules aren't known
+	PluralForms string
+
+	Entries  []POEntry // sorted by the files and lines of the default strings
+	Warnings []string
+}
+
+// ExportPO finds the translatable default strings of the Android project at 'dir'
+// along with their translations in the given locale, e.g. 'fr', as gettext PO
+// messages for translation management systems. Missing translations are empty
+// and potentially outdated translations are marked fuzzy. The items of
+// '<plurals>' resources are exported as plural messages, with a translation for
+// each CLDR plural quantity of the locale.
+func ExportPO(dir, locale string, opts Options) (POCatalog, error) {
+	opts.ArraysAtomic = false // items are messages on their own
+	s := &scanner{dir: dir, opts: opts, skipBlame: opts.SkipOutdated, history: map[string]*xmlStringResources{}}
+	localeStrings, _, _, err := s.findLocaleStrings()
+	if err != nil {
+		return POCatalog{}, err
+	}
+
+	sourceLocale := getSourceLocale(opts)
+	locale = canonicalLocale(locale)
+	if locale == sourceLocale {
+		return POCatalog{}, fmt.Errorf("locale %q is the default locale, which has no translations", locale)
+	}
+
+	if _, ok := localeStrings[locale]; !ok {
+		s.warnf("locale %q has no strings, exporting empty translations", locale)
+	}
+
+	defaultStrings := localeStrings[sourceLocale]
+	translated := localeStrings[locale]
+	catalog := POCatalog{Locale: locale, Language: poLanguage(locale), Entries: make([]POEntry, 0, len(defaultStrings))}
+	forms, ok := lookupPluralForms(catalog.Language)
+	if ok {
+		catalog.PluralForms = forms.header()
+	} else {
+		s.warnf("plural rules of locale %q are unknown, exporting the plural forms that it defines", locale)
+	}
+
+	plurals := map[string]map[string]xmlStringResource{} // plurals name => quantity => default item
+	for _, name := range sortedNames(defaultStrings) {
+		str := defaultStrings[name]
+		if !opts.matchesName(str) || str.TrimmedValue() == "" {
+			continue
+		}
+
+		if str.Type == PluralItemType {
+			if plurals[str.Parent] == nil {
+				plurals[str.Parent] = map[string]xmlStringResource{}
+			}
+
+			plurals[str.Parent][str.Quantity] = str
+			continue
+		}
+
+		entry := POEntry{Context: name, ID: str.TrimmedValue(), Str: []string{""}, Comment: str.Comment, File: s.relPath(str.File), Line: str.Line}
+		if localeStr, ok := translated[name]; ok {
+			entry.Str[0] = localeStr.TrimmedValue()
+			entry.Fuzzy = s.isPOFuzzy(localeStr, str)
+		}
+
+		catalog.Entries = append(catalog.Entries, entry)
+	}
+
+	for parent, items := range plurals {
+		catalog.Entries = append(catalog.Entries, s.pluralsPOEntry(parent, items, translated, forms.Quantities))
+	}
+
+	sort.SliceStable(catalog.Entries, func(i, j int) bool {
+		if catalog.Entries[i].File != catalog.Entries[j].File {
+			return catalog.Entries[i].File < catalog.Entries[j].File
+		}
+
+		return catalog.Entries[i].Line < catalog.Entries[j].Line
+	})
+
+	catalog.Warnings = s.warnings
+	return catalog, nil
+}
+
+// pluralsPOEntry returns the plural message of the '<plurals>' resource with the
+// given name and default items, mapped by their quantities. If 'quantities', i.e.
+// the CLDR plural quantities of the locale, are known, it has a translation for each
+// of them, which is empty if the locale doesn't translate it. Otherwise, its
+// translations are the ones of the quantities that the locale defines, or empty ones
+// for the default quantities if the locale doesn't translate the plurals.
+func (s *scanner) pluralsPOEntry(name string, items map[string]xmlStringResource, translated map[string]xmlStringResource, quantities []string) POEntry {
+	entry := POEntry{Context: name, Str: make([]string, 0)}
+	var first xmlStringResource // default item with the smallest quantity
+	for _, quantity := range pluralQuantities {
+		if item, ok := items[quantity]; ok && first.Name == "" {
+			first = item
+		}
+	}
+
+	entry.ID, entry.IDPlural = first.TrimmedValue(), first.TrimmedValue()
+	if item, ok := items["one"]; ok {
+		entry.ID = item.TrimmedValue()
+	}
+
+	if item, ok := items["other"]; ok {
+		entry.IDPlural = item.TrimmedValue()
+	}
+
+	entry.Comment, entry.File, entry.Line = first.Comment, s.relPath(first.File), first.Line
+	if quantities != nil {
+		for _, quantity := range quantities {
+			localeStr, ok := translated[fmt.Sprintf("%s[%s]", name, quantity)]
+			entry.Str = append(entry.Str, localeStr.TrimmedValue())
+			if item, ok2 := items[quantity]; ok && ok2 && s.isPOFuzzy(localeStr, item) {
+				entry.Fuzzy = true
+			}
+		}
+
+		return entry
+	}
+
+	for _, quantity := range pluralQuantities {
+		if localeStr, ok := translated[fmt.Sprintf("%s[%s]", name, quantity)]; ok {
+			entry.Str = append(entry.Str, localeStr.TrimmedValue())
+			if item, ok := items[quantity]; ok && s.isPOFuzzy(localeStr, item) {
+				entry.Fuzzy = true
+			}
+		}
+	}
+
+	if len(entry.Str) == 0 {
+		for _, quantity := range pluralQuantities {
+			if _, ok := items[quantity]; ok {
+				entry.Str = append(entry.Str, "")
+			}
+		}
+	}
+
+	return entry
+}
+
+// isPOFuzzy checks if the given translation is potentially outdated as per Scan.
+func (s *scanner) isPOFuzzy(localeStr, str xmlStringResource) bool {
+	if !isOutdated(localeStr, str) {
+		return false
+	}
+
+	return !s.opts.IgnoreReformatting || !s.isValueUnchanged(str, localeStr.LastModified)
+}
+
+// poLanguage returns the gettext language code of the given locale qualifier, e.g.
+// 'pt_BR' for 'pt-rBR' and 'sr_Latn' for 'b+sr+Latn'.
+func poLanguage(locale string) string {
+	parts := strings.FieldsFunc(strings.TrimPrefix(locale, "b+"), func(r rune) bool {
+		return r == '-' || r == '+' || r == '_'
+	})
+
+	for i := range parts {
+		if i > 0 && len(parts[i]) == 3 && strings.HasPrefix(parts[i], "r") {
+			parts[i] = strings.TrimPrefix(parts[i], "r")
+		}
+	}
+
+	return strings.Join(parts, "_")
+}
+
+// pluralForms declares the plural rules of a language for gettext, i.e. its CLDR
+// plural quantities in the CLDR order and the C expression that maps a number 'n'
+// to the index of its quantity.
+type pluralForms struct {
+	Quantities []string
+	Expr       string
+}
+
+// header returns the value of the gettext 'Plural-Forms' header for the plural
+// rules.
+func (f pluralForms) header() string {
+	return fmt.Sprintf("nplurals=%d; plural=%s;", len(f.Quantities), f.Expr)
+}
+
+// the plural rules shared by several languages
+var (
+	noPluralForms      = pluralForms{[]string{"other"}, "0"}
+	oneOtherForms      = pluralForms{[]string{"one", "other"}, "(n != 1)"}
+	zeroIsOneForms     = pluralForms{[]string{"one", "other"}, "(n > 1)"} // 'one' includes 0
+	oneManyOtherForms  = pluralForms{[]string{"one", "many", "other"}, "(n == 1 ? 0 : n != 0 && n % 1000000 == 0 ? 1 : 2)"}
+	frenchForms        = pluralForms{[]string{"one", "many", "other"}, "(n == 0 || n == 1 ? 0 : n != 0 && n % 1000000 == 0 ? 1 : 2)"}
+	icelandicForms     = pluralForms{[]string{"one", "other"}, "(n % 10 != 1 || n % 100 == 11)"}
+	serboCroatianForms = pluralForms{[]string{"one", "few", "other"}, "(n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && (n % 100 < 12 || n % 100 > 14) ? 1 : 2)"}
+	eastSlavicForms    = pluralForms{[]string{"one", "few", "many", "other"}, "(n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && (n % 100 < 12 || n % 100 > 14) ? 1 : 2)"}
+	westSlavicForms    = pluralForms{[]string{"one", "few", "many", "other"}, "(n == 1 ? 0 : n >= 2 && n <= 4 ? 1 : 3)"}
+)
+
+// cldrPluralForms maps the gettext language codes to their CLDR plural rules. The
+// quantities that only apply to fractions, e.g. 'other' of 'ru' and 'many' of
+// 'cs', are included since Android apps may define them, but the expressions never
+// select them.
+var cldrPluralForms = map[string]pluralForms{
+	"af": oneOtherForms, "am": zeroIsOneForms, "az": oneOtherForms, "be": eastSlavicForms, "bg": oneOtherForms,
+	"bn": zeroIsOneForms, "bs": serboCroatianForms, "ca": oneManyOtherForms, "cs": westSlavicForms, "da": oneOtherForms,
+	"de": oneOtherForms, "el": oneOtherForms, "en": oneOtherForms, "eo": oneOtherForms, "es": oneManyOtherForms,
+	"et": oneOtherForms, "eu": oneOtherForms, "fa": zeroIsOneForms, "fi": oneOtherForms, "fr": frenchForms,
+	"gl": oneOtherForms, "gu": zeroIsOneForms, "hi": zeroIsOneForms, "hr": serboCroatianForms, "hu": oneOtherForms,
+	"hy": zeroIsOneForms, "id": noPluralForms, "in": noPluralForms, "is": icelandicForms, "it": oneManyOtherForms,
+	"ja": noPluralForms, "ka": oneOtherForms, "kk": oneOtherForms, "km": noPluralForms, "kn": zeroIsOneForms,
+	"ko": noPluralForms, "ky": oneOtherForms, "lo": noPluralForms, "mk": icelandicForms, "ml": oneOtherForms,
+	"mn": oneOtherForms, "mr": oneOtherForms, "ms": noPluralForms, "my": noPluralForms, "nb": oneOtherForms,
+	"ne": oneOtherForms, "nl": oneOtherForms, "nn": oneOtherForms, "no": oneOtherForms, "pt": frenchForms,
+	"pt_PT": oneManyOtherForms, "ru": eastSlavicForms, "sk": westSlavicForms, "sq": oneOtherForms, "sr": serboCroatianForms,
+	"sv": oneOtherForms, "sw": oneOtherForms, "ta": oneOtherForms, "te": oneOtherForms, "th": noPluralForms,
+	"tr": oneOtherForms, "uk": eastSlavicForms, "ur": oneOtherForms, "uz": oneOtherForms, "vi": noPluralForms,
+	"zh": noPluralForms, "zu": zeroIsOneForms,
+	"ar":  {[]string{"zero", "one", "two", "few", "many", "other"}, "(n == 0 ? 0 : n == 1 ? 1 : n == 2 ? 2 : n % 100 >= 3 && n % 100 <= 10 ? 3 : n % 100 >= 11 ? 4 : 5)"},
+	"cy":  {[]string{"zero", "one", "two", "few", "many", "other"}, "(n == 0 ? 0 : n == 1 ? 1 : n == 2 ? 2 : n == 3 ? 3 : n == 6 ? 4 : 5)"},
+	"fil": {[]string{"one", "other"}, "(n % 10 == 4 || n % 10 == 6 || n % 10 == 9)"},
+	"ga":  {[]string{"one", "two", "few", "many", "other"}, "(n == 1 ? 0 : n == 2 ? 1 : n >= 3 && n <= 6 ? 2 : n >= 7 && n <= 10 ? 3 : 4)"},
+	"he":  {[]string{"one", "two", "other"}, "(n == 1 ? 0 : n == 2 ? 1 : 2)"},
+	"iw":  {[]string{"one", "two", "other"}, "(n == 1 ? 0 : n == 2 ? 1 : 2)"},
+	"lt":  {[]string{"one", "few", "many", "other"}, "(n % 10 == 1 && (n % 100 < 11 || n % 100 > 19) ? 0 : n % 10 >= 2 && (n % 100 < 11 || n % 100 > 19) ? 1 : 3)"},
+	"lv":  {[]string{"zero", "one", "other"}, "(n % 10 == 0 || n % 100 >= 11 && n % 100 <= 19 ? 0 : n % 10 == 1 && n % 100 != 11 ? 1 : 2)"},
+	"pl":  {[]string{"one", "few", "many", "other"}, "(n == 1 ? 0 : n % 10 >= 2 && n % 10 <= 4 && (n % 100 < 12 || n % 100 > 14) ? 1 : 2)"},
+	"ro":  {[]string{"one", "few", "other"}, "(n == 1 ? 0 : n == 0 || n % 100 >= 2 && n % 100 <= 19 ? 1 : 2)"},
+	"sl":  {[]string{"one", "two", "few", "other"}, "(n % 100 == 1 ? 0 : n % 100 == 2 ? 1 : n % 100 == 3 || n % 100 == 4 ? 2 : 3)"},
+	"tl":  {[]string{"one", "other"}, "(n % 10 == 4 || n % 10 == 6 || n % 10 == 9)"},
+}
+
+// lookupPluralForms returns the CLDR plural rules of the given gettext language
+// code, e.g. 'pt_BR', or of its language, e.g. 'pt', if the code has no rules of its
+// own. It returns false if neither is known.
+func lookupPluralForms(code string) (pluralForms, bool) {
+	if forms, ok := cldrPluralForms[code]; ok {
+		return forms, true
+	}
+
+	forms, ok := cldrPluralForms[strings.ToLower(strings.SplitN(code, "_", 2)[0])]
+	return forms, ok
+}
@@ -0,0 +1,70 @@
+package translations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExportPOPluralForms(t *testing.T) {
+	dir := writeValuesFiles(t, map[string]string{
+		"res/values/strings.xml": `<resources>
+    <plurals name="songs">
+        <item quantity="one">%d song</item>
+        <item quantity="other">%d songs</item>
+    </plurals>
+</resources>`,
+		"res/values-de/strings.xml": `<resources>
+    <plurals name="songs">
+        <item quantity="one">%d Lied</item>
+        <item quantity="other">%d Lieder</item>
+    </plurals>
+</resources>`,
+		"res/values-fr/strings.xml": `<resources><string name="unrelated">Sans rapport</string></resources>`,
+	})
+
+	tests := []struct {
+		locale      string
+		pluralForms string
+		str         []string
+	}{
+		{"de", "nplurals=2; plural=(n != 1);", []string{"%d Lied", "%d Lieder"}},
+		{"fr", "nplurals=3; plural=(n == 0 || n == 1 ? 0 : n != 0 && n % 1000000 == 0 ? 1 : 2);", []string{"", "", ""}},
+		{"xx", "", []string{"", ""}},
+	}
+
+	for _, test := range tests {
+		catalog, err := ExportPO(dir, test.locale, Options{SkipOutdated: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if catalog.PluralForms != test.pluralForms {
+			t.Errorf("ExportPO(%q) plural forms = %q, want %q", test.locale, catalog.PluralForms, test.pluralForms)
+		}
+
+		if len(catalog.Entries) != 1 || !reflect.DeepEqual(catalog.Entries[0].Str, test.str) {
+			t.Errorf("ExportPO(%q) entries = %v, want translations %q", test.locale, catalog.Entries, test.str)
+		}
+	}
+}
+
+func TestLookupPluralForms(t *testing.T) {
+	tests := []struct {
+		code       string
+		quantities []string
+	}{
+		{"en", []string{"one", "other"}},
+		{"pt_BR", []string{"one", "many", "other"}},
+		{"sr_Latn", []string{"one", "few", "other"}},
+		{"ja", []string{"other"}},
+		{"ar", []string{"zero", "one", "two", "few", "many", "other"}},
+		{"xx", nil},
+	}
+
+	for _, test := range tests {
+		forms, _ := lookupPluralForms(test.code)
+		if !reflect.DeepEqual(forms.Quantities, test.quantities) {
+			t.Errorf("lookupPluralForms(%q) quantities = %v, want %v", test.code, forms.Quantities, test.quantities)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package translations
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// findDivergences finds the strings in the reference project at ReferenceDir and
+// returns the strings whose values in a locale differ from the given locale strings,
+// sorted by their names and locales. The strings that are only present in one of
+// the projects are ignored.
+func (s *scanner) findDivergences(localeStrings localeStringsMap) ([]Divergence, error) {
+	ref := &scanner{dir: s.opts.ReferenceDir, opts: s.opts, skipBlame: true}
+	ref.opts.OnProgress = nil
+	refStrings, _, _, err := ref.findLocaleStrings()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find strings in reference project %s", s.opts.ReferenceDir)
+	}
+
+	for _, warning := range ref.warnings {
+		s.warnf("reference project: %s", warning)
+	}
+
+	divergences := make([]Divergence, 0)
+	for locale, strs := range localeStrings {
+		for name, str := range strs {
+			refStr, ok := refStrings[locale][name]
+			if !ok || refStr.TrimmedValue() == str.TrimmedValue() {
+				continue
+			}
+
+			divergences = append(divergences, Divergence{
+				Name:           name,
+				Locale:         locale,
+				Value:          str.TrimmedValue(),
+				ReferenceValue: refStr.TrimmedValue(),
+			})
+		}
+	}
+
+	sort.Slice(divergences, func(i, j int) bool {
+		if divergences[i].Name != divergences[j].Name {
+			return divergences[i].Name < divergences[j].Name
+		}
+
+		return divergences[i].Locale < divergences[j].Locale
+	})
+
+	return divergences, nil
+}
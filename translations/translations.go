@@ -0,0 +1,1119 @@
+// Package translations finds missing and potentially outdated translations for
+// existing locales in an Android project.
+package translations
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultLocale declares the constant to identify default string resources (resources
+// in 'values' [no suffix] directory)
+const DefaultLocale = "default"
+
+// Resource types of the reported strings.
+const (
+	StringType     = "string"      // '<string>' resources
+	ArrayItemType  = "array-item"  // items of '<string-array>' resources
+	ArrayType      = "array"       // '<string-array>' resources when ArraysAtomic is set
+	PluralItemType = "plural-item" // items of '<plurals>' resources
+)
+
+// Line ranges of the strings that are blamed for their last modified times.
+const (
+	ElementBlameRange = "element" // from the opening tag to the closing tag
+	ValueBlameRange   = "value"   // from the first to the last line of the value
+)
+
+// Options declares the options to configure a Scan.
+type Options struct {
+	ScanArchives           bool     // if true, also find values files inside AAR, JAR and ZIP archives
+	SourceSets             []string // if not empty, only scan values files in these source sets
+	Files                  []string // if not nil, only scan these values files and the ones of the source locale
+	IgnoreFiles            []string // glob patterns of values file names to ignore, besides donottranslate.xml
+	ResRoots               []string // if not empty, only find values files in these directories, e.g. 'app/src/main/res'
+	RequireResParent       bool     // if true, only find values files in 'res' directories, ResRoots or ResDirPatterns
+	ResDirPatterns         []string // glob patterns of resource directory names that RequireResParent accepts besides 'res'
+	ValuesDirPattern       string   // glob pattern of values directory names, DefaultValuesDirPattern if empty
+	ValuesFileExt          string   // extension of values files, matched case-insensitively, DefaultValuesFileExt if empty
+	InputFormat            string   // one of AndroidInputFormat or ARBInputFormat, AndroidInputFormat if empty
+	StrictLocaleValidation bool     // if true, fail if a locale qualifier is malformed instead of skipping it
+	ShowComments           bool     // if true, include translator comments in the report
+	LocaleNames            bool     // if true, include human-readable locale names in the report
+	SuggestNonTranslatable bool     // if true, suggest default strings that look non-translatable
+	ReportNonTranslatable  bool     // if true, list the default strings that are excluded from the translation
+	OutdatedDiffs          bool     // if true, find value-level changes for outdated translations
+	SkipInvalid            bool     // if true, skip values files that can't be read or parsed instead of failing
+	DefaultLocale          string   // locale whose strings are the source of truth, DefaultLocale if empty
+	ShowAuthors            bool     // if true, include committers of the default strings and outdated translations
+	ShowStaleness          bool     // if true, include how far the outdated translations lag behind the default strings
+	CheckPunctuation       bool     // if true, find formatting drift of translations from the default strings
+	MaxLengthRatio         float64  // if positive, find translations that are longer than their default strings by more than this ratio
+	MinLength              int      // minimum length of the translations found with MaxLengthRatio in characters
+	CheckEscapes           bool     // if true, find Android string escaping problems in all locales
+	CheckXliff             bool     // if true, find translations whose '<xliff:g>' placeholder ids differ from the default strings
+	ArraysAtomic           bool     // if true, report each string array as a whole instead of its items
+	RequireFullHistory     bool     // if true, fail in shallow git clones instead of skipping outdated detection
+	SkipOutdated           bool     // if true, skip git blame, so that no outdated translations or committers are found
+	IgnoreReformatting     bool     // if true, don't report translations as outdated if their default value is unchanged
+	IgnoreWhitespaceOnly   bool     // if true, leave out the default strings whose values are only whitespace, e.g. spacers
+	BlameRange             string   // lines blamed for the last modified time of the strings, ElementBlameRange if empty
+	OutdatedPenalty        float64  // between 0 and 1, part of a translation that outdated translations don't count as in the coverage
+	OverlayLocales         []string // locales that only override some default strings, e.g. 'en-rGB', so they are never missing
+	SupportedLocales       []string // if not empty, find the locales that aren't in this list and the listed locales without strings
+
+	// maps the names of default strings to the only locales in which they are
+	// expected to be translated, e.g. a legal notice only required in 'de'. They are
+	// never missing in other locales. Default strings can also declare them with a
+	// '<!-- Locales: de -->' comment directly above them. The items of string arrays
+	// and plurals are matched by the names of their parents.
+	StringLocales map[string][]string
+
+	// if either is set, only the default strings whose names have any of the
+	// NamePrefixes or match NamePattern are reported and counted in the coverage. The
+	// items of string arrays and plurals are matched by the names of their parents.
+	NamePrefixes []string
+	NamePattern  *regexp.Regexp
+
+	// if set, each string that is missing or outdated in at least one locale is passed
+	// to OnString as soon as it is found, in the order of their names, instead of
+	// being collected in Report.Strings. It keeps the memory bounded for huge projects.
+	OnString func(StringResource)
+
+	// if not zero, Now is used as the current time instead of the system clock, e.g.
+	// as the last modified time of the strings whose blame fails, so that reports are
+	// reproducible.
+	Now time.Time
+
+	// if set, OnProgress is called with the number of parsed values files and the
+	// total number of values files before parsing each file and once after parsing
+	// all of them.
+	OnProgress func(done, total int)
+
+	ReferenceDir string // if not empty, find translations that differ from the ones in this Android project
+
+	// if RepoURL is set, link each string to its line on the git host. GitRef is the
+	// commit currently checked out if empty and HostStyle is GitHubHostStyle if empty.
+	RepoURL   string
+	GitRef    string
+	HostStyle string // one of GitHubHostStyle or GitLabHostStyle
+}
+
+// StringResource declares the output structure for a single string resource.
+type StringResource struct {
+	Name             string   `json:"name" toml:"name"`
+	Value            string   `json:"value" toml:"value"`
+	Type             string   `json:"type" toml:"type"` // one of StringType, ArrayItemType, ArrayType or PluralItemType
+	File             string   `json:"file,omitempty" toml:"file,omitempty"`
+	Line             int      `json:"line,omitempty" toml:"line,omitzero"`
+	MissingLocales   []string `json:"missing_locales" toml:"missing_locales"`
+	OutdatedLocales  []string `json:"outdated_locales" toml:"outdated_locales"`
+	IdenticalLocales []string `json:"identical_locales,omitempty" toml:"identical_locales,omitempty"`
+	Comment          string   `json:"comment,omitempty" toml:"comment,omitempty"`                 // only populated when ShowComments is set
+	TranslatorHint   string   `json:"translator_hint,omitempty" toml:"translator_hint,omitempty"` // only populated when ShowComments is set
+	SourceURL        string   `json:"source_url,omitempty" toml:"source_url,omitempty"`           // only populated when RepoURL is set
+
+	// committers of the default string and the outdated translations, only populated
+	// when ShowAuthors is set
+	LastModifiedBy            string            `json:"last_modified_by,omitempty" toml:"last_modified_by,omitempty"`
+	OutdatedLocalesModifiedBy map[string]string `json:"outdated_locales_modified_by,omitempty" toml:"outdated_locales_modified_by,omitempty"`
+
+	// seconds by which the outdated translations were last modified before the
+	// default string, only populated when ShowStaleness is set
+	OutdatedLocalesLag map[string]int64 `json:"outdated_locales_lag,omitempty" toml:"outdated_locales_lag,omitempty"`
+
+	// human-readable locale names, only populated when LocaleNames is set
+	MissingLocalesDisplay  []string `json:"missing_locales_display,omitempty" toml:"missing_locales_display,omitempty"`
+	OutdatedLocalesDisplay []string `json:"outdated_locales_display,omitempty" toml:"outdated_locales_display,omitempty"`
+
+	// last commit of the default string, i.e. the commit that outdated the
+	// translations, only populated when the string is outdated in any locale
+	OutdatingCommit *Commit `json:"outdating_commit,omitempty" toml:"outdating_commit,omitempty"`
+}
+
+// Commit declares the output structure for a git commit.
+type Commit struct {
+	Hash    string `json:"hash" toml:"hash"`
+	Summary string `json:"summary" toml:"summary"` // subject line of the commit message
+}
+
+// DuplicateString declares the output structure for a string name that is defined
+// more than once in the same locale. The first definition is the one that is used,
+// the later ones are ignored.
+type DuplicateString struct {
+	Name      string `json:"name" toml:"name"`
+	Locale    string `json:"locale" toml:"locale"`
+	File      string `json:"file" toml:"file"`             // file of the ignored definition
+	FirstFile string `json:"first_file" toml:"first_file"` // file of the definition that is used
+}
+
+// SuggestedString declares the output structure for a default string that looks
+// like it shouldn't be translated.
+type SuggestedString struct {
+	Name  string `json:"name" toml:"name"`
+	Value string `json:"value" toml:"value"`
+	File  string `json:"file" toml:"file"`
+	Line  int    `json:"line" toml:"line"`
+}
+
+// Reasons of the default strings being non-translatable.
+const (
+	TranslatableAttrReason   = "attribute" // marked with 'translatable="false"'
+	DoNotTranslateFileReason = "file"      // defined in 'donottranslate.xml'
+)
+
+// ExcludedString declares the output structure for a default string that is
+// excluded from the translation.
+type ExcludedString struct {
+	Name   string `json:"name" toml:"name"`
+	File   string `json:"file" toml:"file"`
+	Line   int    `json:"line" toml:"line"`
+	Reason string `json:"reason" toml:"reason"` // one of TranslatableAttrReason or DoNotTranslateFileReason
+}
+
+// PunctuationWarning declares the output structure for an advisory formatting drift
+// of a translation from its default string, e.g. a missing trailing period.
+type PunctuationWarning struct {
+	Name    string `json:"name" toml:"name"`
+	Locale  string `json:"locale" toml:"locale"`
+	Message string `json:"message" toml:"message"`
+}
+
+// LengthWarning declares the output structure for a translation that is much longer
+// than its default string, which may break the UI layouts.
+type LengthWarning struct {
+	Name          string  `json:"name" toml:"name"`
+	Locale        string  `json:"locale" toml:"locale"`
+	Length        int     `json:"length" toml:"length"`                 // in characters
+	DefaultLength int     `json:"default_length" toml:"default_length"` // in characters
+	Ratio         float64 `json:"ratio" toml:"ratio"`                   // rounded to two decimal places
+}
+
+// XliffMismatch declares the output structure for a translation whose '<xliff:g>'
+// placeholder ids differ from the ones of its default string.
+type XliffMismatch struct {
+	Name       string   `json:"name" toml:"name"`
+	Locale     string   `json:"locale" toml:"locale"`
+	MissingIDs []string `json:"missing_ids" toml:"missing_ids"` // ids of the default string that the translation lacks
+	ExtraIDs   []string `json:"extra_ids" toml:"extra_ids"`     // ids of the translation that the default string lacks
+}
+
+// EscapeWarning declares the output structure for an Android string escaping
+// problem in a string that passes XML parsing but fails the Android build.
+type EscapeWarning struct {
+	Name    string `json:"name" toml:"name"`
+	Locale  string `json:"locale" toml:"locale"`
+	Message string `json:"message" toml:"message"`
+}
+
+// Divergence declares the output structure for a string whose value in a locale
+// differs from its value in the reference project.
+type Divergence struct {
+	Name           string `json:"name" toml:"name"`
+	Locale         string `json:"locale" toml:"locale"`
+	Value          string `json:"value" toml:"value"`
+	ReferenceValue string `json:"reference_value" toml:"reference_value"`
+}
+
+// OutdatedDiff declares the output structure for the value-level changes of a
+// potentially outdated translation.
+type OutdatedDiff struct {
+	Name          string `json:"name"`
+	Locale        string `json:"locale"`
+	Translation   string `json:"translation"`
+	PreviousValue string `json:"previous_value"` // default value when the translation was last modified, if known
+	CurrentValue  string `json:"current_value"`
+}
+
+// TypeCount declares the number of default strings of a resource type and the
+// number of those that are translated in a locale.
+type TypeCount struct {
+	Total      int `json:"total"`
+	Translated int `json:"translated"`
+}
+
+// Report declares the result of a Scan.
+type Report struct {
+	Strings                  []StringResource                // strings that are missing or outdated in at least one locale
+	Locales                  []string                        // sorted locales other than the default locale
+	Duplicates               []DuplicateString               // strings defined more than once in a locale
+	SuggestedNonTranslatable []SuggestedString               // only populated when SuggestNonTranslatable is set
+	NonTranslatable          []ExcludedString                // only populated when ReportNonTranslatable is set
+	OutdatedDiffs            []OutdatedDiff                  // only populated when OutdatedDiffs is set
+	PunctuationWarnings      []PunctuationWarning            // only populated when CheckPunctuation is set
+	LengthWarnings           []LengthWarning                 // only populated when MaxLengthRatio is set
+	Divergences              []Divergence                    // only populated when ReferenceDir is set
+	EscapeWarnings           []EscapeWarning                 // only populated when CheckEscapes is set
+	XliffMismatches          []XliffMismatch                 // only populated when CheckXliff is set
+	InvalidLocales           []string                        // skipped locales with malformed qualifiers
+	UnexpectedLocales        []string                        // locales not in SupportedLocales, only populated when it is set
+	TotallyMissingLocales    []string                        // SupportedLocales without any strings, only populated when it is set
+	EmptyLocales             []string                        // locales whose values files define no translatable strings
+	DefaultLanguage          string                          // 'tools:locale' of the default values files, if declared
+	Coverage                 float64                         // translation coverage in percent across all locales
+	LocaleCoverage           map[string]float64              // translation coverage in percent per locale
+	TypeCounts               map[string]map[string]TypeCount // locale => resource type => counts
+	MissingCount             int                             // number of strings missing in at least one locale
+	OutdatedCount            int                             // number of strings outdated in at least one locale
+	AffectedCount            int                             // number of strings missing or outdated in at least one locale
+	Warnings                 []string                        // non-fatal problems encountered during the scan
+	FixedGaps                []Gap                           // only populated by ApplyBaseline
+
+	opts Options // options used for the scan
+}
+
+// scanner holds the options and the warnings of a single Scan.
+type scanner struct {
+	dir       string
+	opts      Options
+	skipBlame bool // if true, don't find the last modified time of the strings
+	warnings  []string
+
+	// language declared by the 'tools:locale' attribute of the default values files
+	defaultLanguage string
+
+	// locales of the parsed values files, including the ones without strings
+	valuesLocales map[string]bool
+
+	// historical resources of the values files, keyed by the file and the time
+	history map[string]*xmlStringResources
+}
+
+// now returns the current time, i.e. the Now option if it is set.
+func (s *scanner) now() time.Time {
+	if !s.opts.Now.IsZero() {
+		return s.opts.Now
+	}
+
+	return time.Now()
+}
+
+// relPath returns the given path relative to the scanned directory so that the
+// paths surfaced to the user aren't prefixed with the project directory. It returns
+// the path as is if it can't be made relative.
+func (s *scanner) relPath(path string) string {
+	rel, err := filepath.Rel(s.dir, path)
+	if err != nil {
+		return path
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// warn records the given error as a warning.
+func (s *scanner) warn(err error) {
+	s.addWarning(err.Error())
+}
+
+// warnf records a warning using the given format and arguments.
+func (s *scanner) warnf(format string, args ...interface{}) {
+	s.addWarning(fmt.Sprintf(format, args...))
+}
+
+// addWarning records the given warning message. Since the messages may contain the
+// paths of the values files from deep in the call stack, e.g. in errors of git
+// commands, the project directory prefix is stripped from them here.
+func (s *scanner) addWarning(msg string) {
+	if dir := filepath.Clean(s.dir); dir != "." {
+		msg = strings.ReplaceAll(msg, dir+string(filepath.Separator), "")
+	}
+
+	s.warnings = append(s.warnings, msg)
+}
+
+// Scan finds the values files in the Android project at 'dir' and reports the
+// default strings that are missing or potentially outdated in other locales.
+func Scan(dir string, opts Options) (Report, error) {
+	s := &scanner{dir: dir, opts: opts, skipBlame: opts.SkipOutdated, history: map[string]*xmlStringResources{}}
+	if !opts.SkipOutdated && isShallowRepository(dir) {
+		const hint = "fetch the full history using 'git fetch --unshallow' or 'fetch-depth: 0' of actions/checkout"
+		if opts.RequireFullHistory {
+			return Report{}, fmt.Errorf("project is a shallow git clone, %s", hint)
+		}
+
+		s.warnf("project is a shallow git clone, skipping potentially outdated translations. To find them, %s", hint)
+		s.skipBlame = true
+	}
+
+	var sourceURLs *sourceURLBuilder
+	if opts.RepoURL != "" {
+		var err error
+		if sourceURLs, err = newSourceURLBuilder(dir, opts); err != nil {
+			return Report{}, err
+		}
+	}
+
+	localeStrings, duplicates, invalidLocales, err := s.findLocaleStrings()
+	if err != nil {
+		return Report{}, err
+	}
+
+	sourceLocale := getSourceLocale(opts)
+	s.checkDefaultLanguage()
+
+	defaultStrings, ok := localeStrings[sourceLocale]
+	if !ok && opts.Files != nil && len(localeStrings) == 0 {
+		// none of the listed files is a values file, so there is nothing to report
+		defaultStrings, ok = map[string]xmlStringResource{}, true
+	}
+
+	if !ok { // shouldn't be true for valid input
+		return Report{}, fmt.Errorf("unable to find string resources for default locale %q", sourceLocale)
+	}
+
+	// locales are iterated in sorted order so that the locale lists of each string
+	// are sorted and the report is reproducible across runs.
+	locales := localeStrings.sortedLocales()
+	// empty default strings have nothing to translate, so they are left out of the
+	// report and the coverage. The names are iterated in sorted order so that the
+	// warnings are reproducible too.
+	names := make([]string, 0, len(defaultStrings))
+	for _, name := range sortedNames(defaultStrings) {
+		str := defaultStrings[name]
+		if !opts.matchesName(str) {
+			delete(defaultStrings, name)
+			continue
+		}
+
+		if str.TrimmedValue() == "" {
+			s.warnf("default string %q in %s is empty, which is likely a mistake", name, s.relPath(str.File))
+			delete(defaultStrings, name)
+			continue
+		}
+
+		if opts.IgnoreWhitespaceOnly && isWhitespaceOnly(str) {
+			delete(defaultStrings, name)
+			continue
+		}
+
+		str.OnlyLocales = opts.expectedLocales(str)
+		defaultStrings[name] = str
+		names = append(names, name)
+	}
+
+	// overlay locales are left out of the total coverage as they aren't meant to
+	// translate all the default strings.
+	overlays := map[string]bool{}
+	coveredLocales := localeStringsMap{}
+	for _, locale := range opts.OverlayLocales {
+		overlays[canonicalLocale(locale)] = true
+	}
+
+	for locale, strs := range localeStrings {
+		if !overlays[locale] {
+			coveredLocales[locale] = strs
+		}
+	}
+
+	strs := make([]StringResource, 0)
+	outdatedNames := map[string]map[string]bool{} // names of the outdated translations per locale
+	var counts affectedCounts
+	for _, name := range names {
+		str := defaultStrings[name]
+		strResource := StringResource{
+			Name:             str.Name,
+			Value:            str.TrimmedValue(),
+			Type:             str.Type,
+			File:             s.relPath(str.File),
+			Line:             str.Line,
+			MissingLocales:   []string{},
+			OutdatedLocales:  []string{},
+			IdenticalLocales: []string{},
+		}
+
+		if opts.ShowComments {
+			strResource.Comment, strResource.TranslatorHint = str.Comment, str.Hint
+		}
+
+		if sourceURLs != nil {
+			strResource.SourceURL = sourceURLs.url(strResource.File, str.Line)
+		}
+
+		if opts.ShowAuthors {
+			strResource.LastModifiedBy = str.LastModifiedBy
+			strResource.OutdatedLocalesModifiedBy = map[string]string{}
+		}
+
+		if opts.ShowStaleness {
+			strResource.OutdatedLocalesLag = map[string]int64{}
+		}
+
+		for _, locale := range locales {
+			localeStr, ok := localeStrings[locale][str.Name]
+			if !isTranslated(localeStrings[locale], str) {
+				if !overlays[locale] && isExpectedIn(str, locale) {
+					strResource.MissingLocales = append(strResource.MissingLocales, locale)
+				}
+
+				continue
+			}
+
+			if !ok { // plural item translated with other quantities
+				continue
+			}
+
+			outdated := isOutdated(localeStr, str)
+			if outdated && opts.IgnoreReformatting {
+				outdated = !s.isValueUnchanged(str, localeStr.LastModified)
+			}
+
+			if outdated {
+				if outdatedNames[locale] == nil {
+					outdatedNames[locale] = map[string]bool{}
+				}
+
+				outdatedNames[locale][str.Name] = true
+				strResource.OutdatedLocales = append(strResource.OutdatedLocales, locale)
+				if opts.ShowAuthors {
+					strResource.OutdatedLocalesModifiedBy[locale] = localeStr.LastModifiedBy
+				}
+
+				if opts.ShowStaleness {
+					strResource.OutdatedLocalesLag[locale] = int64(str.LastModified.Sub(localeStr.LastModified) / time.Second)
+				}
+			}
+
+			if locale != sourceLocale && localeStr.TrimmedValue() == strResource.Value {
+				strResource.IdenticalLocales = append(strResource.IdenticalLocales, locale)
+			}
+		}
+
+		if len(strResource.OutdatedLocales) > 0 && str.LastCommit.Hash != "" {
+			commit := str.LastCommit
+			strResource.OutdatingCommit = &commit
+		}
+
+		if opts.LocaleNames {
+			strResource.MissingLocalesDisplay = localeDisplayNames(strResource.MissingLocales, s.defaultLanguage)
+			strResource.OutdatedLocalesDisplay = localeDisplayNames(strResource.OutdatedLocales, s.defaultLanguage)
+		}
+
+		if len(strResource.MissingLocales)+len(strResource.OutdatedLocales) == 0 {
+			continue
+		}
+
+		counts.add(strResource)
+		if opts.OnString != nil {
+			opts.OnString(strResource)
+		} else {
+			strs = append(strs, strResource)
+		}
+	}
+
+	report := Report{
+		Strings:         strs,
+		Locales:         make([]string, 0, len(localeStrings)),
+		Duplicates:      duplicates,
+		InvalidLocales:  invalidLocales,
+		DefaultLanguage: s.defaultLanguage,
+		Coverage:        computeCoverage(defaultStrings, coveredLocales, outdatedNames, sourceLocale, opts.OutdatedPenalty),
+		LocaleCoverage:  map[string]float64{},
+		TypeCounts:      map[string]map[string]TypeCount{},
+		opts:            opts,
+	}
+
+	for _, locale := range locales {
+		if locale != sourceLocale {
+			report.Locales = append(report.Locales, locale)
+			report.LocaleCoverage[locale] = 100 // overlay locales don't miss any strings
+			if strs, ok := coveredLocales[locale]; ok {
+				report.LocaleCoverage[locale] = computeCoverage(defaultStrings, localeStringsMap{locale: strs}, outdatedNames, sourceLocale, opts.OutdatedPenalty)
+			}
+			report.TypeCounts[locale] = computeTypeCounts(defaultStrings, localeStrings[locale], locale)
+		}
+	}
+
+	report.setCounts(counts)
+	report.EmptyLocales = s.findEmptyLocales(localeStrings, sourceLocale)
+	for _, locale := range report.EmptyLocales {
+		s.warnf("locale %q has values files but no translatable strings", locale)
+	}
+
+	if len(opts.SupportedLocales) > 0 {
+		report.UnexpectedLocales, report.TotallyMissingLocales = compareSupportedLocales(localeStrings, sourceLocale, opts.SupportedLocales)
+		for _, locale := range report.UnexpectedLocales {
+			s.warnf("locale %q has strings but isn't a supported locale", locale)
+		}
+
+		for _, locale := range report.TotallyMissingLocales {
+			s.warnf("supported locale %q has no strings", locale)
+		}
+	}
+
+	if opts.SuggestNonTranslatable {
+		report.SuggestedNonTranslatable = findSuggestedNonTranslatable(defaultStrings)
+		for i, str := range report.SuggestedNonTranslatable {
+			report.SuggestedNonTranslatable[i].File = s.relPath(str.File)
+			s.warnf("string %q looks non-translatable, consider marking it translatable=\"false\"", str.Name)
+		}
+	}
+
+	if opts.ReportNonTranslatable {
+		if report.NonTranslatable, err = s.findNonTranslatable(sourceLocale); err != nil {
+			return Report{}, err
+		}
+	}
+
+	if opts.CheckPunctuation {
+		report.PunctuationWarnings = findPunctuationWarnings(defaultStrings, localeStrings, sourceLocale)
+		for _, w := range report.PunctuationWarnings {
+			s.warnf("string %q in locale %q: %s", w.Name, w.Locale, w.Message)
+		}
+	}
+
+	if opts.MaxLengthRatio > 0 {
+		report.LengthWarnings = findLengthWarnings(defaultStrings, localeStrings, sourceLocale, opts.MaxLengthRatio, opts.MinLength)
+		for _, w := range report.LengthWarnings {
+			s.warnf("string %q in locale %q is %.2fx as long as the default string", w.Name, w.Locale, w.Ratio)
+		}
+	}
+
+	if opts.CheckEscapes {
+		report.EscapeWarnings = findEscapeWarnings(localeStrings)
+		for _, w := range report.EscapeWarnings {
+			s.warnf("string %q in locale %q: %s", w.Name, w.Locale, w.Message)
+		}
+	}
+
+	if opts.CheckXliff {
+		report.XliffMismatches = findXliffMismatches(defaultStrings, localeStrings, sourceLocale)
+		for _, m := range report.XliffMismatches {
+			const warnFmt = "string %q in locale %q: <xliff:g> placeholders don't match the default string, missing [%s], extra [%s]"
+			s.warnf(warnFmt, m.Name, m.Locale, strings.Join(m.MissingIDs, ", "), strings.Join(m.ExtraIDs, ", "))
+		}
+	}
+
+	if opts.ReferenceDir != "" {
+		report.Divergences, err = s.findDivergences(localeStrings)
+		if err != nil {
+			return Report{}, err
+		}
+	}
+
+	if opts.OutdatedDiffs {
+		report.OutdatedDiffs = s.findOutdatedDiffs(strs, defaultStrings, localeStrings)
+	}
+
+	report.Warnings = s.warnings
+	return report, nil
+}
+
+// MissingHistogram maps the numbers of locales to the number of strings of the
+// report that are missing in exactly that many locales. The strings that are only
+// outdated are left out.
+func (r Report) MissingHistogram() map[int]int {
+	histogram := map[int]int{}
+	for _, res := range r.Strings {
+		if len(res.MissingLocales) > 0 {
+			histogram[len(res.MissingLocales)]++
+		}
+	}
+
+	return histogram
+}
+
+// LocalesBelowCoverage returns the sorted locales whose coverage is below the given
+// minimum coverage percentage. 'overrides' maps locales to the minimum coverage that
+// should be used for them instead of 'min'.
+func (r Report) LocalesBelowCoverage(min float64, overrides map[string]float64) []string {
+	locales := make([]string, 0)
+	for _, locale := range r.Locales {
+		threshold, ok := overrides[locale]
+		if !ok {
+			threshold = min
+		}
+
+		if r.LocaleCoverage[locale] < threshold {
+			locales = append(locales, locale)
+		}
+	}
+
+	return locales
+}
+
+// FindValuesFiles returns the values files in the Android project at 'dir' that
+// Scan would parse with the given options, without parsing them. The values files
+// inside archives are left out.
+func FindValuesFiles(dir string, opts Options) ([]string, error) {
+	s := &scanner{dir: dir, opts: opts}
+	valuesFiles, err := s.findScannedValuesFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(valuesFiles))
+	for _, file := range valuesFiles {
+		if !isArchiveEntry(file) {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// findScannedValuesFiles returns the listed values files if Options.Files is set, or
+// else all the values files in the scanned directory, limited to the source sets
+// of Options.SourceSets if it is set. They are sorted as per sortValuesFiles, and
+// then ordered by their source sets if Options.SourceSets is set, since the order
+// decides which of the definitions of a string is used.
+func (s *scanner) findScannedValuesFiles() ([]string, error) {
+	var valuesFiles []string
+	var err error
+	if s.opts.Files != nil {
+		valuesFiles, err = s.findListedValuesFiles(getSourceLocale(s.opts))
+	} else {
+		valuesFiles, err = s.findRootValuesFiles()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	sortValuesFiles(valuesFiles)
+	if len(s.opts.SourceSets) > 0 {
+		valuesFiles = filterBySourceSets(valuesFiles, s.opts.SourceSets)
+	}
+
+	return valuesFiles, nil
+}
+
+// findLocaleStrings finds the values files, or the ARB files if Options.InputFormat
+// is ARBInputFormat, in the scanned directory and returns the translatable strings
+// in them. It also returns the strings that are defined more
+// than once in a locale and the sorted list of skipped invalid locales.
+func (s *scanner) findLocaleStrings() (localeStringsMap, []DuplicateString, []string, error) {
+	valuesFiles, err := s.findScannedValuesFiles()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if s.opts.InputFormat == ARBInputFormat {
+		localeStrings, duplicates, err := s.findARBStrings(valuesFiles)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		for _, dup := range duplicates {
+			const warnFmt = "message %q is defined more than once for locale %q in %s, using the first definition in %s"
+			s.warnf(warnFmt, dup.Name, dup.Locale, dup.File, dup.FirstFile)
+		}
+
+		return localeStrings, duplicates, []string{}, nil
+	}
+
+	valuesFiles, invalidLocales := filterInvalidLocales(valuesFiles)
+	for _, locale := range invalidLocales {
+		if s.opts.StrictLocaleValidation {
+			return nil, nil, nil, fmt.Errorf("unrecognized locale qualifier %q", locale)
+		}
+
+		s.warnf("skipping unrecognized locale qualifier %q", locale)
+	}
+
+	localeStrings, duplicates, err := s.findTranslatableStrings(valuesFiles)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, dup := range duplicates {
+		const warnFmt = "string %q is defined more than once for locale %q in %s, using the first definition in %s"
+		s.warnf(warnFmt, dup.Name, dup.Locale, dup.File, dup.FirstFile)
+	}
+
+	return localeStrings, duplicates, invalidLocales, nil
+}
+
+// findEmptyLocales returns the sorted locales other than the source locale whose
+// values files exist but define no translatable strings, e.g. 'values-fr' with
+// only a 'dimens.xml', to tell barely-started locales apart from absent ones.
+func (s *scanner) findEmptyLocales(localeStrings localeStringsMap, sourceLocale string) []string {
+	empty := make([]string, 0)
+	for locale := range s.valuesLocales {
+		if locale != sourceLocale && len(localeStrings[locale]) == 0 {
+			empty = append(empty, locale)
+		}
+	}
+
+	sort.Strings(empty)
+	return empty
+}
+
+// expectedLocales returns the only locales in which the given default string is
+// expected to be translated as per StringLocales, or else its locales annotation.
+// It returns nil if the string is expected in all locales.
+func (opts Options) expectedLocales(str xmlStringResource) []string {
+	name := str.Name
+	if str.Parent != "" {
+		name = str.Parent
+	}
+
+	locales, ok := opts.StringLocales[name]
+	if !ok && str.Locales != "" {
+		locales = strings.Split(str.Locales, ",")
+	}
+
+	expected := make([]string, 0, len(locales))
+	for _, locale := range locales {
+		if locale = strings.TrimSpace(locale); locale != "" {
+			expected = append(expected, canonicalLocale(locale))
+		}
+	}
+
+	if len(expected) == 0 {
+		return nil
+	}
+
+	return expected
+}
+
+// isExpectedIn checks if the given default string is expected to be translated in
+// the given locale.
+func isExpectedIn(str xmlStringResource, locale string) bool {
+	if str.OnlyLocales == nil {
+		return true
+	}
+
+	for _, expected := range str.OnlyLocales {
+		if expected == locale {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDefaultLanguage warns if the language declared by the 'tools:locale'
+// attribute of the default values files isn't a valid locale qualifier, or if it
+// conflicts with the language of the DefaultLocale option.
+func (s *scanner) checkDefaultLanguage() {
+	if s.defaultLanguage == "" {
+		return
+	}
+
+	if !IsValidLocale(s.defaultLanguage) {
+		s.warnf("default values files declare unrecognized tools:locale %q", s.defaultLanguage)
+		return
+	}
+
+	sourceLocale := getSourceLocale(s.opts)
+	if sourceLocale != DefaultLocale && localeLanguage(sourceLocale) != localeLanguage(s.defaultLanguage) {
+		const warnFmt = "tools:locale %q of the default values files conflicts with the default locale %q"
+		s.warnf(warnFmt, s.defaultLanguage, s.opts.DefaultLocale)
+	}
+}
+
+// matchesName checks if the name of the given default string, or of its parent for
+// the items of string arrays and plurals, matches NamePrefixes or NamePattern. It
+// returns true if neither is set.
+func (opts Options) matchesName(str xmlStringResource) bool {
+	if len(opts.NamePrefixes) == 0 && opts.NamePattern == nil {
+		return true
+	}
+
+	name := str.Name
+	if str.Parent != "" {
+		name = str.Parent
+	}
+
+	for _, prefix := range opts.NamePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return opts.NamePattern != nil && opts.NamePattern.MatchString(name)
+}
+
+// getSourceLocale returns the locale whose strings are the source of truth for the
+// given options.
+func getSourceLocale(opts Options) string {
+	if opts.DefaultLocale != "" {
+		return canonicalLocale(opts.DefaultLocale)
+	}
+
+	if opts.InputFormat == ARBInputFormat {
+		return DefaultARBLocale
+	}
+
+	return DefaultLocale
+}
+
+// FilterByLocale returns a copy of the report that only contains the strings and
+// the findings for the given locale. Locales of the returned strings are limited to
+// the given locale.
+func (r Report) FilterByLocale(locale string) Report {
+	filtered := Report{
+		Strings:         make([]StringResource, 0),
+		Locales:         []string{locale},
+		Duplicates:      make([]DuplicateString, 0),
+		DefaultLanguage: r.DefaultLanguage,
+		Coverage:        r.LocaleCoverage[locale],
+		LocaleCoverage:  map[string]float64{locale: r.LocaleCoverage[locale]},
+		TypeCounts:      map[string]map[string]TypeCount{locale: r.TypeCounts[locale]},
+		opts:            r.opts,
+	}
+
+	for _, res := range r.Strings {
+		res.MissingLocales = filterLocales(res.MissingLocales, locale)
+		res.OutdatedLocales = filterLocales(res.OutdatedLocales, locale)
+		res.IdenticalLocales = filterLocales(res.IdenticalLocales, locale)
+		if len(res.MissingLocales)+len(res.OutdatedLocales) == 0 {
+			continue
+		}
+
+		if r.opts.LocaleNames {
+			res.MissingLocalesDisplay = localeDisplayNames(res.MissingLocales, r.DefaultLanguage)
+			res.OutdatedLocalesDisplay = localeDisplayNames(res.OutdatedLocales, r.DefaultLanguage)
+		}
+
+		if r.opts.ShowAuthors {
+			res.OutdatedLocalesModifiedBy = filterModifiedBy(res.OutdatedLocalesModifiedBy, res.OutdatedLocales)
+		}
+
+		if r.opts.ShowStaleness {
+			res.OutdatedLocalesLag = filterLag(res.OutdatedLocalesLag, res.OutdatedLocales)
+		}
+
+		if len(res.OutdatedLocales) == 0 {
+			res.OutdatingCommit = nil
+		}
+
+		filtered.Strings = append(filtered.Strings, res)
+	}
+
+	for _, dup := range r.Duplicates {
+		if dup.Locale == locale {
+			filtered.Duplicates = append(filtered.Duplicates, dup)
+		}
+	}
+
+	for _, diff := range r.OutdatedDiffs {
+		if diff.Locale == locale {
+			filtered.OutdatedDiffs = append(filtered.OutdatedDiffs, diff)
+		}
+	}
+
+	for _, w := range r.PunctuationWarnings {
+		if w.Locale == locale {
+			filtered.PunctuationWarnings = append(filtered.PunctuationWarnings, w)
+		}
+	}
+
+	for _, w := range r.EscapeWarnings {
+		if w.Locale == locale {
+			filtered.EscapeWarnings = append(filtered.EscapeWarnings, w)
+		}
+	}
+
+	for _, w := range r.LengthWarnings {
+		if w.Locale == locale {
+			filtered.LengthWarnings = append(filtered.LengthWarnings, w)
+		}
+	}
+
+	for _, m := range r.XliffMismatches {
+		if m.Locale == locale {
+			filtered.XliffMismatches = append(filtered.XliffMismatches, m)
+		}
+	}
+
+	for _, divergence := range r.Divergences {
+		if divergence.Locale == locale {
+			filtered.Divergences = append(filtered.Divergences, divergence)
+		}
+	}
+
+	filtered.setCounts(countAffectedStrings(filtered.Strings))
+	return filtered
+}
+
+// filterLocales returns a slice containing only the given locale if it is present
+// in 'locales'. It returns an empty slice otherwise.
+func filterLocales(locales []string, locale string) []string {
+	for _, l := range locales {
+		if l == locale {
+			return []string{locale}
+		}
+	}
+
+	return []string{}
+}
+
+// filterModifiedBy returns a copy of the given locale => committer mapping that only
+// contains the given locales.
+func filterModifiedBy(modifiedBy map[string]string, locales []string) map[string]string {
+	filtered := make(map[string]string, len(locales))
+	for _, locale := range locales {
+		if committer, ok := modifiedBy[locale]; ok {
+			filtered[locale] = committer
+		}
+	}
+
+	return filtered
+}
+
+// filterLag returns a copy of the given locale => lag mapping that only contains
+// the given locales.
+func filterLag(lag map[string]int64, locales []string) map[string]int64 {
+	filtered := make(map[string]int64, len(locales))
+	for _, locale := range locales {
+		if seconds, ok := lag[locale]; ok {
+			filtered[locale] = seconds
+		}
+	}
+
+	return filtered
+}
+
+// MaxLag returns the longest time by which an outdated translation of the string
+// lags behind the default string. It is zero unless ShowStaleness is set.
+func (res StringResource) MaxLag() time.Duration {
+	var max int64
+	for _, seconds := range res.OutdatedLocalesLag {
+		if seconds > max {
+			max = seconds
+		}
+	}
+
+	return time.Duration(max) * time.Second
+}
+
+// affectedCounts holds the number of strings that are missing, potentially outdated
+// and either missing or outdated in at least one locale.
+type affectedCounts struct {
+	missing, outdated, affected int
+}
+
+// add counts the given string.
+func (c *affectedCounts) add(res StringResource) {
+	if len(res.MissingLocales) > 0 {
+		c.missing++
+	}
+
+	if len(res.OutdatedLocales) > 0 {
+		c.outdated++
+	}
+
+	if len(res.MissingLocales)+len(res.OutdatedLocales) > 0 {
+		c.affected++
+	}
+}
+
+// countAffectedStrings returns the counts of the given strings.
+func countAffectedStrings(strs []StringResource) affectedCounts {
+	var counts affectedCounts
+	for _, res := range strs {
+		counts.add(res)
+	}
+
+	return counts
+}
+
+// setCounts sets the count fields of the report to the given counts.
+func (r *Report) setCounts(counts affectedCounts) {
+	r.MissingCount, r.OutdatedCount, r.AffectedCount = counts.missing, counts.outdated, counts.affected
+}
+
+// computeCoverage returns the percentage of default strings that are translated
+// across all locales other than 'sourceLocale'. The translations in 'outdatedNames'
+// only count as '1 - outdatedPenalty' of a translation. If there are no other
+// locales, it returns 100.
+func computeCoverage(defaultStrings map[string]xmlStringResource, localeStrings localeStringsMap, outdatedNames map[string]map[string]bool, sourceLocale string, outdatedPenalty float64) float64 {
+	var total int
+	var translated float64
+	for locale, strs := range localeStrings {
+		if locale == sourceLocale {
+			continue
+		}
+
+		for _, str := range defaultStrings {
+			if !isExpectedIn(str, locale) {
+				continue
+			}
+
+			total++
+			if !isTranslated(strs, str) {
+				continue
+			}
+
+			if outdatedNames[locale][str.Name] {
+				translated += 1 - outdatedPenalty
+			} else {
+				translated++
+			}
+		}
+	}
+
+	if total == 0 {
+		return 100
+	}
+
+	return 100 * translated / float64(total)
+}
+
+// computeTypeCounts returns the number of default strings expected in the given
+// locale and the number of those translated in its strings, grouped by their
+// resource types.
+func computeTypeCounts(defaultStrings map[string]xmlStringResource, strs map[string]xmlStringResource, locale string) map[string]TypeCount {
+	counts := map[string]TypeCount{}
+	for _, str := range defaultStrings {
+		if !isExpectedIn(str, locale) {
+			continue
+		}
+
+		count := counts[str.Type]
+		count.Total++
+		if isTranslated(strs, str) {
+			count.Translated++
+		}
+
+		counts[str.Type] = count
+	}
+
+	return counts
+}
+
+// isOutdated checks if the given translation was last modified before its default
+// string. An atomic string array is outdated if any of its items is outdated.
+func isOutdated(localeStr xmlStringResource, str xmlStringResource) bool {
+	if str.Type == ArrayType {
+		for i := range str.Items {
+			if i < len(localeStr.Items) && isOutdated(localeStr.Items[i], str.Items[i]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	// last modified time is unknown for strings sourced from archives
+	hasTimestamps := !localeStr.LastModified.IsZero() && !str.LastModified.IsZero()
+	return hasTimestamps && localeStr.LastModified.Before(str.LastModified)
+}
+
+// isTranslated checks if the given default string is translated in the given locale
+// strings. Since languages have different plural rules, e.g. Japanese only uses
+// 'other' quantity, a plural item counts as translated if any quantity of its
+// '<plurals>' is translated.
+func isTranslated(strs map[string]xmlStringResource, str xmlStringResource) bool {
+	if localeStr, ok := strs[str.Name]; ok {
+		// an atomic string array is only translated if all its items are translated
+		return len(localeStr.Items) >= len(str.Items)
+	}
+
+	if str.Type != PluralItemType {
+		return false
+	}
+
+	for _, localeStr := range strs {
+		if localeStr.Type == PluralItemType && localeStr.Parent == str.Parent {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package translations
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeValuesFiles writes the given contents of values files, keyed by their paths
+// relative to a new temporary directory, and returns the directory.
+func writeValuesFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "translations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for path, content := range files {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestScanSortsLocales(t *testing.T) {
+	files := map[string]string{
+		"res/values/strings.xml": `<resources><string name="hello">Hello</string><string name="bye">Bye</string></resources>`,
+	}
+
+	// more locales than a map iterates in a fixed order
+	locales := []string{"sv", "de", "pt-rBR", "ru", "cs", "fr", "b+sr+Latn", "ja", "it", "nl", "pl", "es-r419"}
+	for _, locale := range locales {
+		files["res/values-"+locale+"/strings.xml"] = `<resources><string name="bye">Bye</string></resources>`
+	}
+
+	dir := writeValuesFiles(t, files)
+	var first Report
+	for i := 0; i < 10; i++ {
+		report, err := Scan(dir, Options{SkipOutdated: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(report.Strings) != 1 || len(report.Strings[0].MissingLocales) != len(locales) {
+			t.Fatalf("Scan() strings = %v, want 'hello' missing in all locales", report.Strings)
+		}
+
+		if !sort.StringsAreSorted(report.Locales) {
+			t.Fatalf("Scan() locales = %v, want sorted", report.Locales)
+		}
+
+		for _, str := range report.Strings {
+			if !sort.StringsAreSorted(str.MissingLocales) {
+				t.Fatalf("Scan() missing locales of %q = %v, want sorted", str.Name, str.MissingLocales)
+			}
+		}
+
+		if i == 0 {
+			first = report
+		} else if !reflect.DeepEqual(report.Strings, first.Strings) {
+			t.Fatalf("Scan() strings = %v, want %v", report.Strings, first.Strings)
+		}
+	}
+}
+
+func TestComputeCoverage(t *testing.T) {
+	defaultStrings := map[string]xmlStringResource{
+		"hello":  {Name: "hello", Type: StringType},
+		"bye":    {Name: "bye", Type: StringType},
+		"cancel": {Name: "cancel", Type: StringType},
+		"ok":     {Name: "ok", Type: StringType},
+	}
+
+	// 'de' translates all strings, two of them are outdated, and 'fr' translates one
+	localeStrings := localeStringsMap{
+		DefaultLocale: defaultStrings,
+		"de":          defaultStrings,
+		"fr":          {"hello": defaultStrings["hello"]},
+	}
+
+	outdatedNames := map[string]map[string]bool{"de": {"hello": true, "bye": true}}
+	tests := []struct {
+		weight float64
+		want   float64
+	}{
+		{0, 100 * 3 / 8.0},
+		{0.5, 100 * 4 / 8.0},
+		{1, 100 * 5 / 8.0},
+	}
+
+	for _, test := range tests {
+		got := computeCoverage(defaultStrings, localeStrings, outdatedNames, DefaultLocale, 1-test.weight)
+		if got != test.want {
+			t.Errorf("computeCoverage() with outdated weight %v = %v, want %v", test.weight, got, test.want)
+		}
+	}
+
+	if got := computeCoverage(defaultStrings, localeStringsMap{DefaultLocale: defaultStrings}, nil, DefaultLocale, 1); got != 100 {
+		t.Errorf("computeCoverage() without locales = %v, want 100", got)
+	}
+}
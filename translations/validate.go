@@ -0,0 +1,123 @@
+package translations
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// namedResourceTags lists the resource tags that must have a 'name' attribute.
+var namedResourceTags = map[string]bool{
+	"string":       true,
+	"string-array": true,
+	"plurals":      true,
+}
+
+// ValidationError declares the output structure for a problem found in a values
+// file by Validate.
+type ValidationError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+// Validate finds the values files in the Android project at 'dir' and checks that
+// each of them is well-formed XML with a '<resources>' root and that its resource
+// tags have a 'name' attribute. Unlike Scan, it doesn't stop at the first invalid
+// file and returns the problems found in all of them.
+func Validate(dir string, opts Options) ([]ValidationError, error) {
+	s := &scanner{dir: dir, opts: opts}
+	var valuesFiles []string
+	var err error
+	if opts.Files != nil {
+		valuesFiles, err = s.findListedValuesFiles("")
+	} else {
+		valuesFiles, err = s.findRootValuesFiles()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.SourceSets) > 0 {
+		valuesFiles = filterBySourceSets(valuesFiles, opts.SourceSets)
+	}
+
+	validationErrors := make([]ValidationError, 0)
+	for _, file := range valuesFiles {
+		content, err := readValuesFile(file)
+		if err != nil {
+			validationErrors = append(validationErrors, ValidationError{File: s.relPath(file), Message: err.Error()})
+			continue
+		}
+
+		validationErrors = append(validationErrors, validateValuesFile(s.relPath(file), content)...)
+	}
+
+	return validationErrors, nil
+}
+
+// validateValuesFile returns the problems found in the given values file content.
+// A syntax error ends the validation of the file since the decoder can't recover
+// from it.
+func validateValuesFile(file string, content []byte) []ValidationError {
+	validationErrors := make([]ValidationError, 0)
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	depth := 0
+	hasRoot := false
+	for {
+		line := 1 + bytes.Count(content[:decoder.InputOffset()], []byte("\n"))
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			if syntaxErr, ok := err.(*xml.SyntaxError); ok {
+				line = syntaxErr.Line
+			}
+
+			return append(validationErrors, ValidationError{File: file, Line: line, Message: err.Error()})
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				hasRoot = true
+				if t.Name.Local != "resources" {
+					message := fmt.Sprintf("root element is <%s>, expected <resources>", t.Name.Local)
+					validationErrors = append(validationErrors, ValidationError{File: file, Line: line, Message: message})
+				}
+			} else if depth == 1 && namedResourceTags[t.Name.Local] && !hasNameAttr(t) {
+				message := fmt.Sprintf("<%s> is missing the 'name' attribute", t.Name.Local)
+				validationErrors = append(validationErrors, ValidationError{File: file, Line: line, Message: message})
+			}
+
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	if !hasRoot {
+		validationErrors = append(validationErrors, ValidationError{File: file, Line: 1, Message: "root element is missing"})
+	}
+
+	return validationErrors
+}
+
+// hasNameAttr checks if the given element has a non-empty 'name' attribute.
+func hasNameAttr(element xml.StartElement) bool {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == "name" && attr.Value != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,1050 @@
+package translations
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// doNotTranslateFileName as recognised by the Android Developer Tools
+// http://tools.android.com/recent/non-translatablestrings
+const doNotTranslateFileName = "donottranslate.xml"
+
+// Defaults of the discovery options of the values files, i.e. XML files in 'values'
+// directories with or without qualifiers, e.g. 'values-de/strings.xml'.
+const (
+	DefaultValuesDirPattern = "values*"
+	DefaultValuesFileExt    = ".xml"
+)
+
+// archiveEntrySeparator separates the path of an archive from the path of an entry
+// inside it, e.g. 'libs/ui.aar!/res/values/values.xml'.
+const archiveEntrySeparator = "!/"
+
+// xmlTranslatable is a generic struct that can be embedded in other structs
+// to parse values for 'translatable' attribute
+type xmlTranslatable struct {
+	Translatable string `xml:"translatable,attr"`
+}
+
+// IsTranslatable returns false if the value of 'Translatable' attr was set
+// to 'false'. Returns true otherwise.
+func (res *xmlTranslatable) IsTranslatable() bool {
+	return !strings.EqualFold("false", res.Translatable)
+}
+
+// xmlStringResources declares data structure for unmarshalling 'resources' tag in
+// Android values XML files.
+type xmlStringResources struct {
+	xml.Name      `xml:"resources"`
+	ToolsLocale   string                   `xml:"http://schemas.android.com/tools locale,attr"` // language of the file, e.g. 'en'
+	Strings       []xmlStringResource      `xml:"string"`
+	StringArrays  []xmlStringArrayResource `xml:"string-array"`
+	Plurals       []xmlPluralsResource     `xml:"plurals"`
+	IntegerArrays []xmlTypedArrayResource  `xml:"integer-array"` // never translatable
+	TypedArrays   []xmlTypedArrayResource  `xml:"array"`         // never translatable
+
+	// never translatable, only parsed to find translations authored with them by mistake
+	Bools    []xmlValueResource `xml:"bool"`
+	Integers []xmlValueResource `xml:"integer"`
+	Dimens   []xmlValueResource `xml:"dimen"`
+	Colors   []xmlValueResource `xml:"color"`
+}
+
+// xmlStringResource declares data structure for unmarshalling 'string' tags in Android
+// values XML files.
+type xmlStringResource struct {
+	Name           string              `xml:"name,attr"`
+	Value          string              `xml:",chardata"`
+	InnerXML       string              `xml:",innerxml"` // raw content as it appears in the file, e.g. with CDATA markers
+	LastModified   time.Time           `xml:"-"`
+	LastModifiedBy string              `xml:"-"` // committer of the last modification
+	LastCommit     Commit              `xml:"-"` // commit of the last modification
+	File           string              `xml:"-"`
+	Line           int                 `xml:"-"`
+	Comment        string              `xml:"-"`                                               // XML comment directly above the element, if any
+	Hint           string              `xml:"-"`                                               // 'Translators:' comment directly above the element, if any
+	Locales        string              `xml:"-"`                                               // 'Locales:' comment directly above the element, if any
+	OnlyLocales    []string            `xml:"-"`                                               // only set for default strings that are only expected in these locales
+	Space          string              `xml:"http://www.w3.org/XML/1998/namespace space,attr"` // 'xml:space' attribute
+	Quantity       string              `xml:"quantity,attr"`                                   // only set for '<plurals>' items
+	Type           string              `xml:"-"`
+	Parent         string              `xml:"-"` // name of the '<string-array>' or '<plurals>' for items
+	Items          []xmlStringResource `xml:"-"` // only set for atomic '<string-array>' resources
+	xmlTranslatable
+}
+
+// whitespaceEscapeExpr matches the Android escapes of whitespace characters in
+// string values, e.g. '\n', '\t' and '\u0020'.
+var whitespaceEscapeExpr = regexp.MustCompile(`\\[nt]|\\u(0020|00[aA]0|200[0-9aAbB]|202[fF]|3000)`)
+
+// isWhitespaceOnly checks if the value of the given string is only whitespace as
+// displayed by Android, e.g. '" "', '\n' or '\u0020', which is an intentional
+// spacer rather than text to translate.
+func isWhitespaceOnly(str xmlStringResource) bool {
+	value := str.TrimmedValue()
+	value = whitespaceEscapeExpr.ReplaceAllString(strings.ReplaceAll(value, `"`, ""), "")
+	return strings.TrimSpace(value) == ""
+}
+
+// isBlank checks if the given default string is left out of the report for its
+// value, i.e. if it is empty, or only whitespace and IgnoreWhitespaceOnly is set.
+func (s *scanner) isBlank(str xmlStringResource) bool {
+	return str.TrimmedValue() == "" || (s.opts.IgnoreWhitespaceOnly && isWhitespaceOnly(str))
+}
+
+// TrimmedValue returns the value without its leading and trailing whitespace unless
+// 'xml:space' attr is set to 'preserve'. Whitespace inside a double-quoted value,
+// e.g. '"  Hello "', is significant as per Android rules and is retained since the
+// quotes are kept as well.
+func (res *xmlStringResource) TrimmedValue() string {
+	if res.Space == "preserve" {
+		return res.Value
+	}
+
+	return strings.TrimSpace(res.Value)
+}
+
+type xmlStringArrayResource struct {
+	Name string `xml:"name,attr"`
+	// since items have only the value, we can re-use xmlStringResource struct
+	Items []xmlStringResource `xml:"item"`
+	xmlTranslatable
+}
+
+// xmlTypedArrayResource declares data structure for unmarshalling 'integer-array'
+// and 'array' tags in Android values XML files. Their items are integers, colors,
+// resource references and the like, which aren't translated, so they are only
+// checked for human text that belongs in a '<string-array>' instead.
+type xmlTypedArrayResource struct {
+	Name  string   `xml:"name,attr"`
+	Items []string `xml:"item"`
+}
+
+// xmlValueResource declares data structure for unmarshalling 'bool', 'integer',
+// 'dimen' and 'color' tags in Android values XML files. Only their names are
+// needed since their values aren't translated.
+type xmlValueResource struct {
+	Name string `xml:"name,attr"`
+}
+
+// mistypedResource declares a non-string resource in a locale other than the source
+// locale, which may be a translation of a default string with the wrong tag.
+type mistypedResource struct {
+	Name   string
+	Tag    string
+	Locale string
+	File   string
+	Line   int
+}
+
+// xmlPluralsResource declares data structure for unmarshalling 'plurals' tags in
+// Android values XML files.
+type xmlPluralsResource struct {
+	Name  string              `xml:"name,attr"`
+	Items []xmlStringResource `xml:"item"`
+	xmlTranslatable
+}
+
+// localeStringsMap declares the type to map locales => string_name => stringResource
+type localeStringsMap map[string]map[string]xmlStringResource
+
+// sortedLocales returns the locales in the map sorted alphabetically.
+func (m localeStringsMap) sortedLocales() []string {
+	locales := make([]string, 0, len(m))
+	for locale := range m {
+		locales = append(locales, locale)
+	}
+
+	sort.Strings(locales)
+	return locales
+}
+
+// sortedNames returns the names of the given strings sorted alphabetically.
+func sortedNames(strs map[string]xmlStringResource) []string {
+	names := make([]string, 0, len(strs))
+	for name := range strs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// findValuesFiles finds XML files in 'path/**/*/values*'. This function should be
+// compatible with cases where multiple resource directories are in use.
+func (s *scanner) findValuesFiles(path string) ([]string, error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read directory %s", path)
+	}
+
+	valuesFiles := make([]string, 0)
+	for _, file := range files {
+		filePath := filepath.Join(path, file.Name())
+		if isGitIgnored(path, filePath) {
+			continue
+		}
+
+		if file.IsDir() {
+			moreValuesFiles, err := s.findValuesFiles(filePath)
+			if err != nil {
+				return nil, err
+			}
+
+			valuesFiles = append(valuesFiles, moreValuesFiles...)
+		} else if s.opts.ScanArchives && isArchiveFile(filePath) {
+			archiveValuesFiles, err := findArchiveValuesFiles(filePath, s.opts)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, archiveValuesFile := range archiveValuesFiles {
+				if s.hasResParent(archiveValuesFile) {
+					valuesFiles = append(valuesFiles, archiveValuesFile)
+				}
+			}
+		} else {
+			if s.isValuesFile(filePath) {
+				valuesFiles = append(valuesFiles, filePath)
+			}
+		}
+	}
+
+	return valuesFiles, nil
+}
+
+// findRootValuesFiles finds the values files in the scanned directory, or only in
+// the directories of Options.ResRoots, relative to the scanned directory unless
+// absolute, if it is set.
+func (s *scanner) findRootValuesFiles() ([]string, error) {
+	if len(s.opts.ResRoots) == 0 {
+		return s.findValuesFiles(s.dir)
+	}
+
+	valuesFiles := make([]string, 0)
+	seen := map[string]bool{} // roots may be nested
+	for _, root := range s.opts.ResRoots {
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(s.dir, root)
+		}
+
+		rootValuesFiles, err := s.findValuesFiles(root)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range rootValuesFiles {
+			if !seen[file] {
+				seen[file] = true
+				valuesFiles = append(valuesFiles, file)
+			}
+		}
+	}
+
+	return valuesFiles, nil
+}
+
+// findListedValuesFiles returns the values files in Options.Files, relative to the
+// scanned directory unless absolute, and all the values files of the given source
+// locale in the same resource directories, so that the listed files can be compared
+// to the default strings even if those weren't listed. The listed files that aren't
+// values files are ignored and the ones that don't exist are skipped with a warning.
+// If 'sourceLocale' is empty, only the listed files are returned.
+func (s *scanner) findListedValuesFiles(sourceLocale string) ([]string, error) {
+	valuesFiles := make([]string, 0)
+	seen := map[string]bool{}
+	seenResDirs := map[string]bool{}
+	for _, file := range s.opts.Files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(s.dir, file)
+		}
+
+		file = filepath.Clean(file)
+		if !s.isValuesFile(file) || seen[file] {
+			continue
+		}
+
+		if _, err := os.Stat(file); err != nil {
+			s.warnf("skipping listed values file: %s", err)
+			continue
+		}
+
+		seen[file] = true
+		valuesFiles = append(valuesFiles, file)
+		resDir := filepath.Dir(filepath.Dir(file))
+		if sourceLocale == "" || seenResDirs[resDir] {
+			continue
+		}
+
+		seenResDirs[resDir] = true
+		dirs, err := ioutil.ReadDir(resDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read directory %s", resDir)
+		}
+
+		for _, dir := range dirs {
+			valuesDir := filepath.Join(resDir, dir.Name())
+			if !dir.IsDir() || !s.opts.isValuesDir(dir.Name()) {
+				continue
+			}
+
+			if getLocaleForValuesFile(filepath.Join(valuesDir, "strings.xml")) != sourceLocale {
+				continue
+			}
+
+			files, err := ioutil.ReadDir(valuesDir)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to read directory %s", valuesDir)
+			}
+
+			for _, f := range files {
+				sourceFile := filepath.Join(valuesDir, f.Name())
+				if !f.IsDir() && !seen[sourceFile] && s.isValuesFile(sourceFile) {
+					seen[sourceFile] = true
+					valuesFiles = append(valuesFiles, sourceFile)
+				}
+			}
+		}
+	}
+
+	// keep the order deterministic regardless of the order of the list
+	sort.Strings(valuesFiles)
+	return valuesFiles, nil
+}
+
+// sortValuesFiles sorts the given values files by their path segments, e.g.
+// 'res/values/strings.xml' before 'res/values-de/strings.xml', so that the order
+// doesn't depend on the order of Options.ResRoots or Options.Files.
+func sortValuesFiles(files []string) {
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := strings.Split(filepath.ToSlash(files[i]), "/"), strings.Split(filepath.ToSlash(files[j]), "/")
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+
+		return len(a) < len(b)
+	})
+}
+
+// isArchiveFile checks if the given path has an '.aar', '.jar' or '.zip' extension.
+func isArchiveFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".aar", ".jar", ".zip":
+		return true
+	default:
+		return false
+	}
+}
+
+// isArchiveEntry checks if the given path points to an entry inside an archive.
+func isArchiveEntry(path string) bool {
+	return strings.Contains(path, archiveEntrySeparator)
+}
+
+// findArchiveValuesFiles finds values files inside the archive at the given path
+// without extracting it. The returned paths are of the form 'archive!/entry'.
+func findArchiveValuesFiles(path string, opts Options) ([]string, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open archive %s", path)
+	}
+
+	defer reader.Close()
+	valuesFiles := make([]string, 0)
+	for _, entry := range reader.File {
+		if isValuesFile(entry.Name, opts) {
+			valuesFiles = append(valuesFiles, path+archiveEntrySeparator+entry.Name)
+		}
+	}
+
+	return valuesFiles, nil
+}
+
+// readValuesFile reads the content of the given values file and converts it to UTF-8
+// as per decodeValuesFile.
+func readValuesFile(path string) ([]byte, error) {
+	content, err := readRawValuesFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeValuesFile(content)
+}
+
+// readRawValuesFile reads the content of the given values file. If the path points
+// to an entry inside an archive, the entry is read from the archive's zip stream.
+func readRawValuesFile(path string) ([]byte, error) {
+	if !isArchiveEntry(path) {
+		return ioutil.ReadFile(path)
+	}
+
+	split := strings.SplitN(path, archiveEntrySeparator, 2)
+	reader, err := zip.OpenReader(split[0])
+	if err != nil {
+		return nil, err
+	}
+
+	defer reader.Close()
+	for _, entry := range reader.File {
+		if entry.Name != split[1] {
+			continue
+		}
+
+		entryReader, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		defer entryReader.Close()
+		return ioutil.ReadAll(entryReader)
+	}
+
+	return nil, fmt.Errorf("entry %s not found in archive %s", split[1], split[0])
+}
+
+// isValuesFile checks the name of the parent of the given path as per isValuesDir.
+// It also checks the file extension of the path. If the file name is equal to
+// doNotTranslateFileName, or matches any of the Options.IgnoreFiles glob patterns,
+// it returns false. If the parent is a values directory and file extension equals
+// Options.ValuesFileExt, i.e. '.xml' by default, it returns true. False otherwise.
+// The directories with configuration qualifiers, e.g. 'values-night' and
+// 'values-de-v21', hold alternatives of the same strings rather than translations,
+// so their files aren't values files either.
+func isValuesFile(path string, opts Options) bool {
+	name := filepath.Base(path)
+	if doNotTranslateFileName == name {
+		return false
+	}
+
+	for _, pattern := range opts.IgnoreFiles {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return false
+		}
+	}
+
+	ext := opts.ValuesFileExt
+	if ext == "" {
+		ext = DefaultValuesFileExt
+	}
+
+	parent := filepath.Base(filepath.Dir(path))
+	return opts.isValuesDir(parent) && strings.EqualFold(ext, filepath.Ext(path)) && !hasConfigQualifiers(path)
+}
+
+// isValuesDir checks if the given directory name matches Options.ValuesDirPattern,
+// i.e. 'values*' by default. The qualifiers follow the first '-' of the name.
+func (opts Options) isValuesDir(name string) bool {
+	pattern := opts.ValuesDirPattern
+	if pattern == "" {
+		pattern = DefaultValuesDirPattern
+	}
+
+	matched, _ := filepath.Match(pattern, name)
+	return matched
+}
+
+// isValuesFile checks if the given path is a values file as per isValuesFile,
+// ignoring the files of Options.IgnoreFiles, and if it has a resource parent as
+// per hasResParent. If Options.InputFormat is ARBInputFormat, it checks if the path
+// is an ARB file as per isARBFile instead.
+func (s *scanner) isValuesFile(path string) bool {
+	if s.opts.InputFormat == ARBInputFormat {
+		return isARBFile(path, s.opts.IgnoreFiles)
+	}
+
+	return isValuesFile(path, s.opts) && s.hasResParent(path)
+}
+
+// hasResParent checks if the values directory of the given values file is in a
+// resource directory, i.e. a directory named 'res', one of Options.ResRoots or a
+// directory whose name matches any of Options.ResDirPatterns. It always returns
+// true unless Options.RequireResParent is set, since Android projects may declare
+// resource directories with any name.
+func (s *scanner) hasResParent(path string) bool {
+	if !s.opts.RequireResParent {
+		return true
+	}
+
+	resDir := filepath.Dir(filepath.Dir(path))
+	name := filepath.Base(resDir)
+	if name == "res" {
+		return true
+	}
+
+	for _, pattern := range s.opts.ResDirPatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	for _, root := range s.opts.ResRoots {
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(s.dir, root)
+		}
+
+		if filepath.Clean(root) == resDir {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getSourceSet returns the name of the source set that the given path belongs to,
+// i.e. the path segment following the last 'src' segment, e.g. 'main' for
+// 'app/src/main/res/values/strings.xml'. It returns an empty string if the path
+// isn't in a source set.
+func getSourceSet(path string) string {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i := len(segments) - 2; i >= 0; i-- {
+		if segments[i] == "src" {
+			return segments[i+1]
+		}
+	}
+
+	return ""
+}
+
+// filterBySourceSets returns the given files that belong to one of the given source
+// sets. The returned files are ordered by the position of their source set in
+// 'sourceSets' so that strings from the later source sets override the strings from
+// the earlier ones, e.g. 'flavorA' overrides 'main' for 'main,flavorA'.
+func filterBySourceSets(files []string, sourceSets []string) []string {
+	filtered := make([]string, 0)
+	for _, sourceSet := range sourceSets {
+		for _, file := range files {
+			if getSourceSet(file) == sourceSet {
+				filtered = append(filtered, file)
+			}
+		}
+	}
+
+	return filtered
+}
+
+// findTranslatableStrings looks for '<string>' tags with '<resources>' tag as its root
+// in given files. It parses all the string tags without 'translatable="fasle"' attribute.
+// It returns a mapping of locale to their strings where locale is suffix of 'values-'.
+// If no suffix is present, i.e. 'values', DefaultLocale constant is used to identify those
+// values. It also returns the strings that are defined more than once in a locale. Only
+// the first of those definitions is used so that the value, the line and the last
+// modified time of a string always come from the same definition. The definitions in
+// other source sets and in directories with MCC or MNC qualifiers aren't duplicates,
+// and the later files override the earlier ones instead. So the order of the files,
+// see findScannedValuesFiles, decides which definition is used.
+func (s *scanner) findTranslatableStrings(files []string) (localeStringsMap, []DuplicateString, error) {
+	strResources := make(localeStringsMap, 0)
+	duplicates := make([]DuplicateString, 0)
+	seenNames := map[string]map[string]string{}   // file of the first definition of each tag and name
+	localeNames := map[string]map[string]string{} // file of the last definition of each tag and name per locale
+	mistyped := make([]mistypedResource, 0)       // non-string resources of the locales other than the source locale
+	for i, file := range files {
+		if s.opts.OnProgress != nil {
+			s.opts.OnProgress(i, len(files))
+		}
+
+		content, resources, comments, err := parseValuesFile(file)
+		if err != nil {
+			if !s.opts.SkipInvalid {
+				return nil, nil, err
+			}
+
+			s.warnf("skipping invalid values file: %s", err)
+			continue
+		}
+
+		locale := getLocaleForValuesFile(file)
+		if s.valuesLocales == nil {
+			s.valuesLocales = map[string]bool{}
+		}
+
+		s.valuesLocales[locale] = true
+		if locale == DefaultLocale && resources.ToolsLocale != "" {
+			if s.defaultLanguage == "" {
+				s.defaultLanguage = resources.ToolsLocale
+			} else if s.defaultLanguage != resources.ToolsLocale {
+				const warnFmt = "tools:locale %q in %s differs from %q of the other default values files, using %q"
+				s.warnf(warnFmt, resources.ToolsLocale, s.relPath(file), s.defaultLanguage, s.defaultLanguage)
+			}
+		}
+
+		strResCount := len(resources.Strings) + len(resources.StringArrays) + len(resources.Plurals)
+		if _, ok := strResources[locale]; !ok && strResCount > 0 {
+			strResources[locale] = map[string]xmlStringResource{}
+		}
+
+		// source sets and directories with other qualifiers, e.g. 'values-mcc310',
+		// legitimately override each other's strings, so only look for duplicates
+		// within the same values directory of a source set.
+		seenKey := getSourceSet(file) + "/" + filepath.Base(filepath.Dir(file))
+		if _, ok := seenNames[seenKey]; !ok {
+			seenNames[seenKey] = map[string]string{}
+		}
+
+		if _, ok := localeNames[locale]; !ok {
+			localeNames[locale] = map[string]string{}
+		}
+
+		isDuplicate := func(tag, name string) bool {
+			if firstFile, ok := seenNames[seenKey][tag+"/"+name]; ok {
+				duplicates = append(duplicates, DuplicateString{
+					Name:      name,
+					Locale:    locale,
+					File:      s.relPath(file),
+					FirstFile: s.relPath(firstFile),
+				})
+
+				return true
+			}
+
+			// without Options.SourceSets, the source sets that override each other
+			// may not be built together, e.g. 'debug' and 'release', so their order
+			// is arbitrary
+			if lastFile, ok := localeNames[locale][tag+"/"+name]; ok && len(s.opts.SourceSets) == 0 && getSourceSet(lastFile) != getSourceSet(file) {
+				const warnFmt = "%s %q of locale %q in %s overrides the one in %s, set source sets to choose which one is used"
+				s.warnf(warnFmt, tag, name, locale, s.relPath(file), s.relPath(lastFile))
+			}
+
+			seenNames[seenKey][tag+"/"+name] = file
+			localeNames[locale][tag+"/"+name] = file
+			return false
+		}
+
+		for _, str := range resources.Strings {
+			if isDuplicate("string", str.Name) || !str.IsTranslatable() {
+				continue
+			}
+
+			str.Type = StringType
+			str.File = file
+			str.Comment, str.Hint = comments[str.Name].Text, comments[str.Name].Hint
+			str.Locales = comments[str.Name].Locales
+			if locale == getSourceLocale(s.opts) && s.isBlank(str) {
+				// left out of the report, so there is nothing to locate or blame
+				strResources[locale][str.Name] = str
+				continue
+			}
+
+			start, count, err := getLineRange(content, "string", str.Name)
+			if err == nil {
+				str.Line = start
+				if s.canBlame(file) {
+					start, count = s.blameLineRange(content, "string", str.Name, -1, start, count)
+					str.LastModified, str.LastModifiedBy, str.LastCommit, err = getLastModified(file, start, count)
+				}
+			}
+
+			if err != nil {
+				s.warn(err)
+				if !isArchiveEntry(file) {
+					str.LastModified = s.now()
+				}
+			}
+
+			strResources[locale][str.Name] = str
+		}
+
+		for _, strArr := range resources.StringArrays {
+			if isDuplicate("string-array", strArr.Name) || !strArr.IsTranslatable() {
+				continue
+			}
+
+			items := make([]xmlStringResource, 0, len(strArr.Items))
+			for i, strArrItem := range strArr.Items {
+				strArrItem.Name = fmt.Sprintf("%s[%d]", strArr.Name, i)
+				strArrItem.Type = ArrayItemType
+				strArrItem.Parent = strArr.Name
+				strArrItem.File = file
+				strArrItem.Comment, strArrItem.Hint = comments[strArr.Name].Text, comments[strArr.Name].Hint
+				strArrItem.Locales = comments[strArr.Name].Locales
+				start, count, err := getItemLineRange(content, "string-array", strArr.Name, i)
+				if err == nil {
+					strArrItem.Line = start
+					if s.canBlame(file) {
+						start, count = s.blameLineRange(content, "string-array", strArr.Name, i, start, count)
+						strArrItem.LastModified, strArrItem.LastModifiedBy, strArrItem.LastCommit, err = getLastModified(file, start, count)
+					}
+				}
+
+				if err != nil {
+					s.warn(err)
+					if !isArchiveEntry(file) {
+						strArrItem.LastModified = s.now()
+					}
+				}
+
+				items = append(items, strArrItem)
+			}
+
+			if !s.opts.ArraysAtomic {
+				for _, item := range items {
+					strResources[locale][item.Name] = item
+				}
+
+				continue
+			}
+
+			arr := newAtomicArray(strArr.Name, items)
+			arr.File = file
+			arr.Comment, arr.Hint = comments[strArr.Name].Text, comments[strArr.Name].Hint
+			arr.Locales = comments[strArr.Name].Locales
+			arr.Line, _, _ = getLineRange(content, "string-array", strArr.Name)
+			strResources[locale][arr.Name] = arr
+		}
+
+		if locale == getSourceLocale(s.opts) {
+			for _, arr := range resources.TypedArrays {
+				if containsText(arr.Items) {
+					const warnFmt = "array %q in %s looks like it contains text, which isn't translated. Use a <string-array> instead"
+					s.warnf(warnFmt, arr.Name, s.relPath(file))
+				}
+			}
+		}
+
+		if locale != getSourceLocale(s.opts) {
+			valueResources := map[string][]xmlValueResource{
+				"bool":    resources.Bools,
+				"integer": resources.Integers,
+				"dimen":   resources.Dimens,
+				"color":   resources.Colors,
+			}
+
+			for tag, resources := range valueResources {
+				for _, res := range resources {
+					line, _, _ := getLineRange(content, tag, res.Name)
+					mistyped = append(mistyped, mistypedResource{Name: res.Name, Tag: tag, Locale: locale, File: file, Line: line})
+				}
+			}
+		}
+
+		for _, plurals := range resources.Plurals {
+			if isDuplicate("plurals", plurals.Name) || !plurals.IsTranslatable() {
+				continue
+			}
+
+			for i, pluralsItem := range plurals.Items {
+				pluralsItem.Name = fmt.Sprintf("%s[%s]", plurals.Name, pluralsItem.Quantity)
+				pluralsItem.Type = PluralItemType
+				pluralsItem.Parent = plurals.Name
+				pluralsItem.File = file
+				pluralsItem.Comment, pluralsItem.Hint = comments[plurals.Name].Text, comments[plurals.Name].Hint
+				pluralsItem.Locales = comments[plurals.Name].Locales
+				start, count, err := getItemLineRange(content, "plurals", plurals.Name, i)
+				if err == nil {
+					pluralsItem.Line = start
+					if s.canBlame(file) {
+						start, count = s.blameLineRange(content, "plurals", plurals.Name, i, start, count)
+						pluralsItem.LastModified, pluralsItem.LastModifiedBy, pluralsItem.LastCommit, err = getLastModified(file, start, count)
+					}
+				}
+
+				if err != nil {
+					s.warn(err)
+					if !isArchiveEntry(file) {
+						pluralsItem.LastModified = s.now()
+					}
+				}
+
+				strResources[locale][pluralsItem.Name] = pluralsItem
+			}
+		}
+	}
+
+	if s.opts.OnProgress != nil {
+		s.opts.OnProgress(len(files), len(files))
+	}
+
+	s.checkMistypedResources(strResources[getSourceLocale(s.opts)], mistyped)
+	return strResources, duplicates, nil
+}
+
+// checkMistypedResources warns about the given non-string resources of the locales
+// whose names are also the names of translatable default strings, string arrays or
+// plurals, e.g. a '<bool name="app_name">' in 'values-de', which usually is a
+// translation that was mis-merged and is reported as missing without a hint.
+func (s *scanner) checkMistypedResources(defaultStrings map[string]xmlStringResource, mistyped []mistypedResource) {
+	names := map[string]bool{}
+	for _, str := range defaultStrings {
+		if str.Parent != "" {
+			names[str.Parent] = true
+		} else {
+			names[str.Name] = true
+		}
+	}
+
+	sort.SliceStable(mistyped, func(i, j int) bool {
+		if mistyped[i].File != mistyped[j].File {
+			return mistyped[i].File < mistyped[j].File
+		}
+
+		return mistyped[i].Line < mistyped[j].Line
+	})
+
+	for _, res := range mistyped {
+		if names[res.Name] {
+			const warnFmt = "translatable string %q is defined as a <%s> for locale %q in %s:%d, which is likely a mis-merged translation"
+			s.warnf(warnFmt, res.Name, res.Tag, res.Locale, s.relPath(res.File), res.Line)
+		}
+	}
+}
+
+// parseValuesFile reads and parses the given values file. It returns the raw content
+// of the file, its string resources and the comments preceding its elements.
+func parseValuesFile(file string) ([]byte, *xmlStringResources, map[string]elementComment, error) {
+	content, err := readValuesFile(file)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "unable to read file at %s", file)
+	}
+
+	resources := &xmlStringResources{}
+	if err := xml.Unmarshal(content, resources); err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "unable to parse XML file at %s", file)
+	}
+
+	fillTextValues(resources)
+
+	comments, err := findElementComments(content)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "unable to parse XML file at %s", file)
+	}
+
+	return content, resources, comments, nil
+}
+
+// newAtomicArray returns a single string for the '<string-array>' with the given name
+// and items. Its value is the values of the items on separate lines and it is last
+// modified when any of its items is last modified.
+func newAtomicArray(name string, items []xmlStringResource) xmlStringResource {
+	arr := xmlStringResource{Name: name, Type: ArrayType, Items: items}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		values = append(values, item.TrimmedValue())
+		if item.LastModified.After(arr.LastModified) {
+			arr.LastModified, arr.LastModifiedBy, arr.LastCommit = item.LastModified, item.LastModifiedBy, item.LastCommit
+		}
+	}
+
+	arr.Value = strings.Join(values, "\n")
+	return arr
+}
+
+// canBlame checks if the last modified time of the strings in the given file should
+// be found using 'git blame'. Git blame can't look inside archives.
+func (s *scanner) canBlame(file string) bool {
+	return !s.skipBlame && !isArchiveEntry(file)
+}
+
+// translatorHintPrefix is the prefix of the comments that are meant as guidance for
+// translators, e.g. '<!-- Translators: "Save" is a verb here -->'.
+const translatorHintPrefix = "Translators:"
+
+// localesAnnotationPrefix is the prefix of the comments that list the only locales in
+// which a default string is expected to be translated, e.g. '<!-- Locales: de -->'.
+const localesAnnotationPrefix = "Locales:"
+
+// elementComment declares the comments directly preceding an element.
+type elementComment struct {
+	Text    string // the last comment that isn't a translator hint or a locales annotation
+	Hint    string // the last translator hint without its prefix
+	Locales string // the last locales annotation without its prefix
+}
+
+// findElementComments returns a mapping of element names to the XML comments that
+// directly precede them in the given values file content. Only the direct children
+// of the root element, e.g. '<string>', '<string-array>' and '<plurals>', are
+// considered. The comments starting with translatorHintPrefix are returned as hints
+// and the ones starting with localesAnnotationPrefix as locales annotations, separate
+// from the other comments.
+func findElementComments(content []byte) (map[string]elementComment, error) {
+	comments := map[string]elementComment{}
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	depth := 0
+	lastComment := elementComment{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return comments, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.Comment:
+			if depth != 1 {
+				break
+			}
+
+			text := strings.TrimSpace(string(t))
+			if strings.HasPrefix(text, translatorHintPrefix) {
+				lastComment.Hint = strings.TrimSpace(strings.TrimPrefix(text, translatorHintPrefix))
+			} else if strings.HasPrefix(text, localesAnnotationPrefix) {
+				lastComment.Locales = strings.TrimSpace(strings.TrimPrefix(text, localesAnnotationPrefix))
+			} else {
+				lastComment.Text = text
+			}
+		case xml.CharData:
+			if depth == 1 && len(bytes.TrimSpace(t)) > 0 {
+				lastComment = elementComment{}
+			}
+		case xml.StartElement:
+			if depth == 1 && lastComment != (elementComment{}) {
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "name" {
+						comments[attr.Value] = lastComment
+					}
+				}
+			}
+
+			lastComment = elementComment{}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// getLineRange returns the line range of the first element with the given 'tag'
+// and 'name' attribute in 'fileContent'. The range spans from the opening tag to
+// its closing tag. It returns the following positional values
+// 1. start: line number where the opening tag starts
+// 2. count: total line count of the element itself.
+// 3. error: if the element was not found in 'fileContent'
+func getLineRange(fileContent []byte, tag, name string) (int, int, error) {
+	startOffset, endOffset, err := findElement(fileContent, tag, name)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return toLineRange(fileContent, startOffset, endOffset)
+}
+
+// getItemLineRange returns the line range of the item at 'index' in the element with
+// the given 'tag', i.e. '<string-array>' or '<plurals>', and 'name' attribute. It
+// returns the same positional values as getLineRange.
+func getItemLineRange(fileContent []byte, tag, name string, index int) (int, int, error) {
+	startOffset, endOffset, err := findItem(fileContent, tag, name, index)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return toLineRange(fileContent, startOffset, endOffset)
+}
+
+// getValueLineRange returns the line range of the value of the element with the
+// given 'tag' and 'name' attribute, or of its item at 'index' if it isn't negative,
+// i.e. the lines from the first to the last non-whitespace character between its
+// opening and closing tags. If the value is empty, it returns the line range of
+// the element itself. It returns the same positional values as getLineRange.
+func getValueLineRange(fileContent []byte, tag, name string, index int) (int, int, error) {
+	var startOffset, endOffset int
+	var err error
+	if index < 0 {
+		startOffset, endOffset, err = findElement(fileContent, tag, name)
+	} else {
+		startOffset, endOffset, err = findItem(fileContent, tag, name, index)
+	}
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	element := fileContent[startOffset:endOffset]
+	openingEnd, closingStart := bytes.IndexByte(element, '>')+1, bytes.LastIndex(element, []byte("</"))
+	if openingEnd < 1 || closingStart < openingEnd { // self-closing
+		return toLineRange(fileContent, startOffset, endOffset)
+	}
+
+	value := element[openingEnd:closingStart]
+	trimmed := bytes.TrimLeft(value, " \t\r\n")
+	if len(trimmed) == 0 {
+		return toLineRange(fileContent, startOffset, endOffset)
+	}
+
+	valueStart := startOffset + openingEnd + len(value) - len(trimmed)
+	valueEnd := startOffset + openingEnd + len(bytes.TrimRight(value, " \t\r\n"))
+	return toLineRange(fileContent, valueStart, valueEnd)
+}
+
+// blameLineRange returns the line range to blame for the last modified time of the
+// element with the given 'tag' and 'name' attribute, or of its item at 'index' if it
+// isn't negative. 'start' and 'count' are the line range of the element or the item
+// itself, which is returned as is unless Options.BlameRange is ValueBlameRange.
+func (s *scanner) blameLineRange(fileContent []byte, tag, name string, index, start, count int) (int, int) {
+	if s.opts.BlameRange != ValueBlameRange {
+		return start, count
+	}
+
+	valueStart, valueCount, err := getValueLineRange(fileContent, tag, name, index)
+	if err != nil {
+		return start, count
+	}
+
+	return valueStart, valueCount
+}
+
+// findItem returns the start and end offsets of the item at 'index' in the element
+// with the given 'tag', i.e. '<string-array>' or '<plurals>', and 'name' attribute.
+func findItem(fileContent []byte, tag, name string, index int) (int, int, error) {
+	elementStart, elementEnd, err := findElement(fileContent, tag, name)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	itemExpr := regexp.MustCompile(`(?s)<item(\s[^>]*)?(/>|>.*?</item\s*>)`)
+	items := itemExpr.FindAllIndex(fileContent[elementStart:elementEnd], -1)
+	if index >= len(items) {
+		const errFmt = "item %d of <%s name=%q> is not found"
+		return 0, 0, fmt.Errorf(errFmt, index, tag, name)
+	}
+
+	return elementStart + items[index][0], elementStart + items[index][1], nil
+}
+
+// findElement returns the start and end offsets of the first element with the given
+// 'tag' and 'name' attribute in 'fileContent'.
+func findElement(fileContent []byte, tag, name string) (int, int, error) {
+	const exprFmt = `<%s\s[^>]*?name\s*=\s*"%s"[^>]*>`
+	openingExpr := regexp.MustCompile(fmt.Sprintf(exprFmt, regexp.QuoteMeta(tag), regexp.QuoteMeta(name)))
+	loc := openingExpr.FindIndex(fileContent)
+	if loc == nil {
+		const errFmt = "element <%s name=%q> is not found"
+		return 0, 0, fmt.Errorf(errFmt, tag, name)
+	}
+
+	if bytes.HasSuffix(fileContent[loc[0]:loc[1]], []byte("/>")) {
+		return loc[0], loc[1], nil
+	}
+
+	closingExpr := regexp.MustCompile(fmt.Sprintf(`</%s\s*>`, regexp.QuoteMeta(tag)))
+	closingLoc := closingExpr.FindIndex(fileContent[loc[1]:])
+	if closingLoc == nil {
+		const errFmt = "closing tag for element <%s name=%q> is not found"
+		return 0, 0, fmt.Errorf(errFmt, tag, name)
+	}
+
+	return loc[0], loc[1] + closingLoc[1], nil
+}
+
+// toLineRange converts the given offsets in 'fileContent' to a line range. It
+// returns the same positional values as getLineRange.
+func toLineRange(fileContent []byte, startOffset, endOffset int) (int, int, error) {
+	start := 1 + bytes.Count(fileContent[:startOffset], []byte("\n"))
+	count := 1 + bytes.Count(fileContent[startOffset:endOffset], []byte("\n"))
+	return start, count, nil
+}
@@ -0,0 +1,155 @@
+package translations
+
+import (
+	"encoding/xml"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// xliffPlaceholderExpr matches the opening tags of '<xliff:g>' placeholders, e.g.
+// '<xliff:g id="count" example="3">'. The first group is the 'id' attribute.
+var xliffPlaceholderExpr = regexp.MustCompile(`<xliff:g\b[^>]*?\bid\s*=\s*["']([^"']*)["']`)
+
+// fillTextValues sets the values of the strings, string array items and plurals
+// items in the given resources to their text content. The XML decoder only collects
+// the character data directly inside an element, so the placeholders of
+// '<xliff:g>' tags, e.g. '%d' in '<xliff:g id="count">%d</xliff:g>', and the text
+// of styling tags, e.g. '<b>', would be left out of the values otherwise.
+func fillTextValues(resources *xmlStringResources) {
+	fill := func(res *xmlStringResource) {
+		if !strings.Contains(res.InnerXML, "<") {
+			return
+		}
+
+		if text, err := textContent(res.InnerXML); err == nil {
+			res.Value = text
+		}
+	}
+
+	for i := range resources.Strings {
+		fill(&resources.Strings[i])
+	}
+
+	for i := range resources.StringArrays {
+		for j := range resources.StringArrays[i].Items {
+			fill(&resources.StringArrays[i].Items[j])
+		}
+	}
+
+	for i := range resources.Plurals {
+		for j := range resources.Plurals[i].Items {
+			fill(&resources.Plurals[i].Items[j])
+		}
+	}
+}
+
+// textContent returns the character data of the given inner XML of an element,
+// including the character data of its nested elements.
+func textContent(innerXML string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader("<text>" + innerXML + "</text>"))
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return text.String(), nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if data, ok := token.(xml.CharData); ok {
+			text.Write(data)
+		}
+	}
+}
+
+// xliffIDs returns the distinct ids of the '<xliff:g>' placeholders in the given
+// string, or in all of its items if it is an atomic string array, in sorted order.
+func xliffIDs(str xmlStringResource) []string {
+	innerXML := str.InnerXML
+	for _, item := range str.Items {
+		innerXML += item.InnerXML
+	}
+
+	ids := make([]string, 0)
+	seen := map[string]bool{}
+	for _, match := range xliffPlaceholderExpr.FindAllStringSubmatch(innerXML, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			ids = append(ids, match[1])
+		}
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+// findXliffMismatches returns the translations whose '<xliff:g>' placeholder ids
+// differ from the ones of their default strings, sorted by the string names and
+// locales. Since plurals quantities differ between languages, plurals items are
+// only checked for the ids that the 'other' default item doesn't have.
+func findXliffMismatches(defaultStrings map[string]xmlStringResource, localeStrings localeStringsMap, sourceLocale string) []XliffMismatch {
+	mismatches := make([]XliffMismatch, 0)
+	for _, locale := range localeStrings.sortedLocales() {
+		if locale == sourceLocale {
+			continue
+		}
+
+		for name, localeStr := range localeStrings[locale] {
+			defaultName := name
+			if localeStr.Type == PluralItemType {
+				defaultName = localeStr.Parent + "[other]"
+			}
+
+			defaultStr, ok := defaultStrings[defaultName]
+			if !ok {
+				continue
+			}
+
+			expected, actual := xliffIDs(defaultStr), xliffIDs(localeStr)
+			missing, extra := diffIDs(expected, actual), diffIDs(actual, expected)
+			if localeStr.Type == PluralItemType {
+				missing = []string{}
+			}
+
+			if len(missing)+len(extra) > 0 {
+				mismatches = append(mismatches, XliffMismatch{
+					Name:       name,
+					Locale:     locale,
+					MissingIDs: missing,
+					ExtraIDs:   extra,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(mismatches, func(i, j int) bool {
+		if mismatches[i].Name != mismatches[j].Name {
+			return mismatches[i].Name < mismatches[j].Name
+		}
+
+		return mismatches[i].Locale < mismatches[j].Locale
+	})
+
+	return mismatches
+}
+
+// diffIDs returns the ids in 'a' that aren't in 'b'.
+func diffIDs(a, b []string) []string {
+	inB := map[string]bool{}
+	for _, id := range b {
+		inB[id] = true
+	}
+
+	diff := make([]string, 0)
+	for _, id := range a {
+		if !inB[id] {
+			diff = append(diff, id)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ashutoshgngwr/android-translations/translations"
+)
+
+// watch scans the project and prints the report whenever its values files change,
+// until it receives SIGINT or SIGTERM. The values files and their directories are
+// polled every '--watch-interval', and a change is only picked up once the files
+// stay unchanged for a whole interval, so that rapid saves cause a single scan.
+// Polling is used rather than file system notifications since it needs no extra
+// dependency and also works for the bind mounts of Docker containers and network
+// file systems, which often don't deliver notifications. The git history isn't
+// used, so that the scans are fast.
+func watch() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	files := watchScan()
+	last, pending := fingerprint(files), ""
+	for {
+		select {
+		case <-signals:
+			fmt.Fprintln(os.Stderr, "stopped watching")
+			return
+		case <-time.After(watchInterval):
+		}
+
+		current := fingerprint(files)
+		if current == last {
+			pending = ""
+			continue
+		}
+
+		if current != pending { // still changing, wait for another interval
+			pending = current
+			continue
+		}
+
+		files = watchScan()
+		last, pending = fingerprint(files), ""
+	}
+}
+
+// watchScan scans the project without the git history and prints the report. It
+// returns the values files to watch. Errors are printed instead of exiting, so that
+// the files can be fixed while watching.
+func watchScan() []string {
+	opts := scanOptions()
+	opts.SkipOutdated = true
+	files, err := translations.FindValuesFiles(projectDir, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+	}
+
+	if isTerminal(os.Stdout) {
+		fmt.Print("\033[H\033[2J") // clear the screen before each report
+	}
+
+	report, err := translations.Scan(projectDir, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+	} else {
+		report = report.WithLocaleAliases(localeAliases)
+		sortStrings(report.Strings)
+		for _, warning := range report.Warnings {
+			fmt.Fprintln(os.Stderr, "warning:", warning)
+		}
+
+		title := renderTitle(reportTitleData(len(report.Strings), report.MissingCount, report.OutdatedCount, report.AffectedCount))
+		printOutput(mustRenderReport(title, report))
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: watching %d values files, press Ctrl+C to stop\n", time.Now().Format("15:04:05"), len(files))
+	return files
+}
+
+// fingerprint returns a string that changes whenever any of the given files or
+// their directories is modified, e.g. when a values file is saved, added to or
+// removed from a values directory.
+func fingerprint(files []string) string {
+	paths := map[string]bool{}
+	for _, file := range files {
+		paths[file] = true
+		paths[filepath.Dir(file)] = true               // values directory
+		paths[filepath.Dir(filepath.Dir(file))] = true // resource directory
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+
+	sort.Strings(sorted)
+	var b strings.Builder
+	for _, path := range sorted {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d\n", path, info.ModTime().UnixNano(), info.Size())
+		} else {
+			fmt.Fprintf(&b, "%s:-\n", path)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ashutoshgngwr/android-translations/translations"
+	"github.com/pkg/errors"
+)
+
+// webhookReport declares the JSON body that is posted to the webhook when the JSON
+// webhook format is being used.
+type webhookReport struct {
+	Title          string                        `json:"title"`
+	Coverage       float64                       `json:"coverage"`
+	LocaleCoverage map[string]float64            `json:"locale_coverage"`
+	MissingCount   int                           `json:"missing_count"`
+	OutdatedCount  int                           `json:"outdated_count"`
+	AffectedCount  int                           `json:"total_affected"`
+	Strings        []translations.StringResource `json:"strings"`
+}
+
+// slackMessage declares the JSON body of a Slack incoming webhook message.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// postWebhook posts the given report to the webhook URL in the requested webhook
+// format. It returns an error if the request fails or the response status isn't
+// 2xx.
+func postWebhook(title string, report translations.Report) error {
+	var body interface{}
+	if webhookFormat == "slack" {
+		body = slackMessage{Text: renderSlackText(title, report)}
+	} else {
+		body = webhookReport{
+			Title:          title,
+			Coverage:       report.Coverage,
+			LocaleCoverage: report.LocaleCoverage,
+			MissingCount:   report.MissingCount,
+			OutdatedCount:  report.OutdatedCount,
+			AffectedCount:  report.AffectedCount,
+			Strings:        withSelectedFields(report.Strings),
+		}
+	}
+
+	content, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal webhook body")
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return errors.Wrap(err, "unable to post report to webhook")
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("webhook responded with %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+// renderSlackText renders the summary of the given report, i.e. the coverage and the
+// counts, as Slack 'mrkdwn' text.
+func renderSlackText(title string, report translations.Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*\n", title)
+	fmt.Fprintf(&b, "Coverage: %.2f%%\n", report.Coverage)
+	for _, locale := range report.Locales {
+		fmt.Fprintf(&b, "• `%s`: %.2f%%\n", locale, report.LocaleCoverage[locale])
+	}
+
+	fmt.Fprintf(&b, "Missing: %d, potentially outdated: %d, total affected: %d",
+		report.MissingCount, report.OutdatedCount, report.AffectedCount)
+
+	return b.String()
+}
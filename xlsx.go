@@ -0,0 +1,190 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ashutoshgngwr/android-translations/translations"
+	"github.com/pkg/errors"
+)
+
+// xlsxHeader is the header row of each sheet of the XLSX report. The 'New Value'
+// column is left empty for the translators to fill in.
+var xlsxHeader = []string{"Name", "Default Value", "Status", "New Value"}
+
+// xlsxRels is the package relationships part of the XLSX report.
+const xlsxRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" ` +
+	`Target="xl/workbook.xml"/></Relationships>`
+
+// xlsxPart declares a single part of the XLSX package.
+type xlsxPart struct {
+	Path    string
+	Content string
+}
+
+// mustRenderXLSX renders the given report as an Office Open XML spreadsheet with a
+// sheet for each locale. Each sheet lists the strings that are missing or outdated
+// in its locale. Since the standard library can write zip archives and XML, the
+// minimal set of parts that spreadsheet applications require is written directly.
+// It panics on encountering an error while writing the archive.
+func mustRenderXLSX(report translations.Report) string {
+	sheets := make([][][]string, 0, len(report.Locales))
+	names := make([]string, 0, len(report.Locales))
+	for _, locale := range report.Locales {
+		rows := [][]string{xlsxHeader}
+		for _, res := range report.Strings {
+			if containsLocale(res.MissingLocales, locale) {
+				rows = append(rows, []string{res.Name, res.Value, translations.MissingGap, ""})
+			} else if containsLocale(res.OutdatedLocales, locale) {
+				rows = append(rows, []string{res.Name, res.Value, translations.OutdatedGap, ""})
+			}
+		}
+
+		sheets = append(sheets, rows)
+		names = append(names, xlsxSheetName(locale))
+	}
+
+	if len(sheets) == 0 { // a workbook must have at least one sheet
+		sheets = append(sheets, [][]string{xlsxHeader})
+		names = append(names, "Translations")
+	}
+
+	// some readers expect the content types to be the first entry
+	parts := []xlsxPart{
+		{"[Content_Types].xml", xlsxContentTypes(len(sheets))},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook(names)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(sheets))},
+	}
+
+	for i, rows := range sheets {
+		parts = append(parts, xlsxPart{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), xlsxSheet(rows)})
+	}
+
+	var content bytes.Buffer
+	archive := zip.NewWriter(&content)
+	for _, part := range parts {
+		w, err := archive.Create(part.Path)
+		if err != nil {
+			panic(errors.Wrap(err, "failed to write XLSX report"))
+		}
+
+		if _, err := io.WriteString(w, part.Content); err != nil {
+			panic(errors.Wrap(err, "failed to write XLSX report"))
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		panic(errors.Wrap(err, "failed to write XLSX report"))
+	}
+
+	return content.String()
+}
+
+// containsLocale checks if the given locale is in the given list of locales.
+func containsLocale(locales []string, locale string) bool {
+	for _, l := range locales {
+		if l == locale {
+			return true
+		}
+	}
+
+	return false
+}
+
+// xlsxSheetName returns the given name without the characters that aren't allowed
+// in sheet names, truncated to 31 characters.
+func xlsxSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	if len(name) > 31 {
+		name = name[:31]
+	}
+
+	return name
+}
+
+// xlsxContentTypes returns the content types part for the given number of sheets.
+func xlsxContentTypes(sheetCount int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" `+
+			`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+// xlsxWorkbook returns the workbook part with the sheets of the given names.
+func xlsxWorkbook(names []string) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" `)
+	b.WriteString(`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range names {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xlsxEscape(name), i+1, i+1)
+	}
+
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+// xlsxWorkbookRels returns the relationships of the workbook to the given number of
+// sheets.
+func xlsxWorkbookRels(sheetCount int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" `+
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" `+
+			`Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// xlsxSheet returns the worksheet part with the given rows. The values are written
+// as inline strings so that no shared strings part is needed.
+func xlsxSheet(rows [][]string) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, value := range row {
+			// there are only a few columns, so a single letter is enough for each
+			fmt.Fprintf(&b, `<c r="%c%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, 'A'+j, i+1, xlsxEscape(value))
+		}
+
+		b.WriteString(`</row>`)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// xlsxEscape escapes the given text for use in XML content and attribute values.
+func xlsxEscape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}